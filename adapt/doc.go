@@ -0,0 +1,6 @@
+// Package adapt bridges the queues in this module with Go channels.
+// The queues are not channels, so they can't be used in a `select` statement. A `adapt.Pipe` uses a `micro.Ring`
+// as a fast internal buffer between two channels, with a pump goroutine moving values from one side to the other,
+// so the buffer can still be used by code that has to interoperate with `select` based APIs.
+// The inverse is also available: `adapt.Drain` moves every value received from a channel into a ring.
+package adapt
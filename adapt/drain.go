@@ -0,0 +1,32 @@
+package adapt
+
+import (
+	"context"
+
+	"github.com/probably-not/q/micro"
+)
+
+// Drain moves every value received from the channel into the ring, blocking while the ring is full.
+// It returns a `nil` error once the channel is closed and every value has been pushed, the context's error
+// if the context is done first, or `micro.ErrClosed` if the ring is closed.
+// A value that was already received from the channel when the push failed is not lost: it is returned
+// along with `true` and the error, so that the caller can deliver it elsewhere. Otherwise, `false` is returned.
+// Drain is a producer of the ring, so nothing else may push to the ring while it runs.
+func Drain[T any](ctx context.Context, ch <-chan T, ring *micro.Ring[T]) (T, bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, false, ctx.Err()
+		case v, ok := <-ch:
+			if !ok {
+				var zero T
+				return zero, false, nil
+			}
+
+			if err := ring.PushCtx(ctx, v); err != nil {
+				return v, true, err
+			}
+		}
+	}
+}
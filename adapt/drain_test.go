@@ -0,0 +1,96 @@
+package adapt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/probably-not/q/micro"
+)
+
+func TestDrain(t *testing.T) {
+	testCases := []struct {
+		expectedErr         error
+		desc                string
+		queueSizeFactor     int
+		values              int
+		expectedPushed      int
+		expectedConsumed    int
+		closeRing           bool
+		expectedUndelivered bool
+	}{
+		{
+			desc:             "Every value is pushed once the channel is closed",
+			queueSizeFactor:  6,
+			values:           10,
+			expectedPushed:   10,
+			expectedConsumed: 10,
+			expectedErr:      nil,
+		},
+		{
+			desc:                "Draining into a full ring returns the deadline error and the value that could not be pushed",
+			queueSizeFactor:     2,
+			values:              10,
+			expectedPushed:      3,
+			expectedConsumed:    4,
+			expectedUndelivered: true,
+			expectedErr:         context.DeadlineExceeded,
+		},
+		{
+			desc:                "Draining into a closed ring returns the closed error and the value that could not be pushed",
+			queueSizeFactor:     6,
+			values:              10,
+			expectedPushed:      0,
+			expectedConsumed:    1,
+			expectedUndelivered: true,
+			closeRing:           true,
+			expectedErr:         micro.ErrClosed,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			r := micro.NewRing[int](tC.queueSizeFactor)
+			if tC.closeRing {
+				r.Close()
+			}
+
+			ch := make(chan int, tC.values)
+			for i := 0; i < tC.values; i++ {
+				ch <- i
+			}
+			close(ch)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			undelivered, hasUndelivered, err := Drain(ctx, ch, r)
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+
+			if consumed := tC.values - len(ch); tC.expectedConsumed != consumed {
+				subT.Errorf("expected %d values to be consumed from the channel, got %d", tC.expectedConsumed, consumed)
+			}
+
+			// The value that was consumed but not pushed is the one after the pushed values
+			if tC.expectedUndelivered != hasUndelivered {
+				subT.Errorf("expected hasUndelivered to be %t, got %t", tC.expectedUndelivered, hasUndelivered)
+			}
+
+			if hasUndelivered && undelivered != tC.expectedPushed {
+				subT.Errorf("expected the undelivered value to be %d, got %d", tC.expectedPushed, undelivered)
+			}
+
+			if pushed := r.Len(); tC.expectedPushed != pushed {
+				subT.Errorf("expected %d values to be pushed, got %d", tC.expectedPushed, pushed)
+			}
+
+			for i := 0; i < tC.expectedPushed; i++ {
+				if v, ok := r.TryPop(); !ok || v != i {
+					subT.Errorf("expected the popped value to be %d but got %d", i, v)
+				}
+			}
+		})
+	}
+}
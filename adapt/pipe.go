@@ -0,0 +1,113 @@
+package adapt
+
+import (
+	"time"
+
+	"github.com/probably-not/q/micro"
+)
+
+// Pipe buffers the values sent to In in a `micro.Ring`, and delivers them in order to Out.
+// A pump goroutine is the only producer and the only consumer of the ring, so the ring must not
+// be used by anything else once it is handed to the Pipe.
+// Closing In stops the Pipe from accepting values. The values that are already buffered are
+// still delivered to Out, after which Out is closed.
+type Pipe[T any] struct {
+	ring   *micro.Ring[T]
+	in     chan T
+	out    chan T
+	window time.Duration
+}
+
+// NewPipe creates a Pipe around the ring, and starts its pump goroutine.
+// The window is the batching window of the pump: after a value is received from In, the pump keeps
+// receiving from In for up to the window (or until the ring is full) before delivering to Out.
+// A window of zero delivers every value as soon as possible.
+func NewPipe[T any](ring *micro.Ring[T], window time.Duration) *Pipe[T] {
+	p := &Pipe[T]{
+		ring:   ring,
+		in:     make(chan T),
+		out:    make(chan T),
+		window: window,
+	}
+
+	go p.pump()
+	return p
+}
+
+// In returns the channel that values are sent to the Pipe on.
+func (p *Pipe[T]) In() chan<- T {
+	return p.in
+}
+
+// Out returns the channel that the Pipe delivers values on.
+func (p *Pipe[T]) Out() <-chan T {
+	return p.out
+}
+
+func (p *Pipe[T]) pump() {
+	defer close(p.out)
+
+	in := p.in
+	var pending T
+	hasPending := false
+
+	for {
+		if !hasPending {
+			pending, hasPending = p.ring.TryPop()
+		}
+
+		if in == nil && !hasPending {
+			return
+		}
+
+		// A nil channel is never selected, which disables receiving while the ring
+		// is full, and sending while there is nothing to deliver.
+		recv := in
+		if p.ring.IsFull() {
+			recv = nil
+		}
+
+		var send chan T
+		if hasPending {
+			send = p.out
+		}
+
+		select {
+		case v, ok := <-recv:
+			if !ok {
+				in = nil
+				continue
+			}
+
+			p.ring.TryPush(v)
+			if p.window > 0 && !p.gather() {
+				in = nil
+			}
+		case send <- pending:
+			var zero T
+			pending, hasPending = zero, false
+		}
+	}
+}
+
+// gather keeps receiving values from In into the ring until the batching window passes or the ring is full.
+// It returns `false` if In was closed while gathering.
+func (p *Pipe[T]) gather() bool {
+	timer := time.NewTimer(p.window)
+	defer timer.Stop()
+
+	for !p.ring.IsFull() {
+		select {
+		case v, ok := <-p.in:
+			if !ok {
+				return false
+			}
+
+			p.ring.TryPush(v)
+		case <-timer.C:
+			return true
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,82 @@
+package adapt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/probably-not/q/micro"
+)
+
+func TestPipe(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		queueSizeFactor int
+		window          time.Duration
+		values          int
+	}{
+		{
+			desc:            "Values are delivered in order without a batching window",
+			queueSizeFactor: 6,
+			window:          0,
+			values:          1000,
+		},
+		{
+			desc:            "Values are delivered in order with a batching window",
+			queueSizeFactor: 6,
+			window:          100 * time.Microsecond,
+			values:          1000,
+		},
+		{
+			desc:            "Values are delivered in order when the ring is smaller than the values",
+			queueSizeFactor: 2,
+			window:          100 * time.Microsecond,
+			values:          1000,
+		},
+		{
+			desc:            "Closing an unused pipe closes its output",
+			queueSizeFactor: 6,
+			window:          0,
+			values:          0,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			p := NewPipe(micro.NewRing[int](tC.queueSizeFactor), tC.window)
+
+			go func() {
+				for i := 0; i < tC.values; i++ {
+					p.In() <- i
+				}
+				close(p.In())
+			}()
+
+			received := 0
+			for v := range p.Out() {
+				if v != received {
+					subT.Errorf("expected the received value to be %d but got %d", received, v)
+				}
+				received++
+			}
+
+			if tC.values != received {
+				subT.Errorf("expected %d values to be received, got %d", tC.values, received)
+			}
+		})
+	}
+}
+
+func TestPipeSelect(t *testing.T) {
+	p := NewPipe(micro.NewRing[int](6), 0)
+	defer close(p.In())
+
+	p.In() <- 1
+
+	select {
+	case v := <-p.Out():
+		if v != 1 {
+			t.Errorf("expected the received value to be %d but got %d", 1, v)
+		}
+	case <-time.After(1 * time.Second):
+		t.Errorf("expected the buffered value to be selectable on the output")
+	}
+}
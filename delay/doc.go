@@ -0,0 +1,10 @@
+// Package delay contains a queue of jobs that become poppable only once their deadline has passed.
+// Scheduled jobs wait in a hierarchical timer wheel, in the style of Varghese and Lauck, where every level of the wheel
+// covers 64 times the span of the level below it, so scheduling and expiring a job costs the same no matter how far
+// away its deadline is. A background goroutine advances the wheel every tick, and moves the jobs that come due
+// into a `micro.Ring`, so consumers pop due jobs from the same lock-free fast path as any other queue.
+// The wheel goroutine is the single producer of the ring, while jobs can be scheduled from any number of goroutines,
+// and popped by any number of consumers. Jobs that come due while the ring is full wait for room, in deadline order.
+// Deadlines are rounded up to the next tick, so a job is never popped before its deadline, and is popped
+// up to a tick after it, plus however long the ring stays full.
+package delay
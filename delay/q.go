@@ -0,0 +1,171 @@
+package delay
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/probably-not/q/internal/check"
+	"github.com/probably-not/q/micro"
+)
+
+// DefaultTick is the tick of a Q that is created with a zero or negative tick.
+const DefaultTick = time.Millisecond
+
+// Q is a queue of jobs that become poppable once their deadline has passed.
+type Q[T any] struct {
+	start time.Time
+	ring  *micro.Ring[T]
+	done  chan struct{}
+	wheel *wheel[T]
+	// ready holds the jobs that came due while the ring was full, in deadline order.
+	ready []T
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	tick  time.Duration
+}
+
+// New creates a delay queue with a ring of the size factor, and starts the goroutine that advances its timer wheel
+// every tick. If the tick is zero or negative, DefaultTick is used. Close must be called to stop the goroutine.
+func New[T any](queueSizeFactor int, tick time.Duration) *Q[T] {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+
+	q := &Q[T]{
+		start: time.Now(),
+		ring:  micro.NewRing[T](queueSizeFactor),
+		done:  make(chan struct{}),
+		wheel: &wheel[T]{},
+		tick:  tick,
+	}
+
+	q.wg.Add(1)
+	go q.run()
+	return q
+}
+
+// NewChecked creates a new delay queue after validating the size factor.
+// If the factor can't be used with the ring, `micro.ErrFactorZero` or `micro.ErrFactorTooLarge` is returned.
+func NewChecked[T any](queueSizeFactor int, tick time.Duration) (*Q[T], error) {
+	if err := check.FactorU32(queueSizeFactor); err != nil {
+		return nil, err
+	}
+
+	return New[T](queueSizeFactor, tick), nil
+}
+
+// Schedule schedules the job to become poppable once the delay has passed.
+// A zero or negative delay makes the job poppable right away, as long as the ring has room for it.
+// If the queue is closed, `micro.ErrClosed` is returned.
+func (q *Q[T]) Schedule(v T, after time.Duration) error {
+	return q.ScheduleAt(v, time.Now().Add(after))
+}
+
+// ScheduleAt schedules the job to become poppable once the deadline has passed.
+// If the queue is closed, `micro.ErrClosed` is returned.
+func (q *Q[T]) ScheduleAt(v T, deadline time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.ring.IsClosed() {
+		return micro.ErrClosed
+	}
+
+	// The deadline is rounded up to the next tick, so that the job is never popped early
+	now := time.Now()
+	due := uint64((deadline.Sub(q.start) + q.tick - 1) / q.tick)
+	if !deadline.After(now) || !q.wheel.insert(v, due) {
+		q.ready = append(q.ready, v)
+		q.flush()
+	}
+
+	return nil
+}
+
+// TryPop will attempt to pop a job that is due.
+// It returns the job along with `true` if a job was popped, or the zero value of `T`
+// along with `false` if no job is due, or the queue is closed and drained.
+// If another consumer commits the same position first, TryPop retries until it either
+// commits a position of its own or finds no job due.
+func (q *Q[T]) TryPop() (T, bool) {
+	return q.ring.TryPop()
+}
+
+// PopCtx will block until a job is due, or until the context is done.
+// If the context is done before a job is due, the context's error is returned,
+// and if the queue is closed and drained, `micro.ErrClosed` is returned.
+func (q *Q[T]) PopCtx(ctx context.Context) (T, error) {
+	return q.ring.PopCtx(ctx)
+}
+
+// Len returns the number of jobs that are due and waiting to be popped.
+func (q *Q[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.ring.Len() + len(q.ready)
+}
+
+// Scheduled returns the number of jobs that are scheduled and not yet due.
+func (q *Q[T]) Scheduled() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.wheel.size
+}
+
+// Close stops the timer wheel and closes the ring, after which Schedule returns `micro.ErrClosed`.
+// Jobs that are already in the ring are drained by the consumers, while jobs that are not yet in the ring
+// are dropped. Closing more than once is a no-op.
+func (q *Q[T]) Close() {
+	q.mu.Lock()
+	if q.ring.IsClosed() {
+		q.mu.Unlock()
+		return
+	}
+
+	q.ring.Close()
+	close(q.done)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
+
+func (q *Q[T]) run() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.done:
+			return
+		case now := <-ticker.C:
+			q.mu.Lock()
+			q.wheel.advance(uint64(now.Sub(q.start)/q.tick), func(v T) {
+				q.ready = append(q.ready, v)
+			})
+			q.flush()
+			q.mu.Unlock()
+		}
+	}
+}
+
+// flush pushes the jobs that are due to the ring, until the ring is full. It must be called with the lock held,
+// which makes whoever holds the lock the single producer of the ring.
+func (q *Q[T]) flush() {
+	n := 0
+	for n < len(q.ready) && q.ring.TryPush(q.ready[n]) {
+		n++
+	}
+
+	// The remaining jobs are moved to the front, so that the slice doesn't grow while the ring is full
+	rest := copy(q.ready, q.ready[n:])
+	var zero T
+	for i := rest; i < len(q.ready); i++ {
+		q.ready[i] = zero
+	}
+	q.ready = q.ready[:rest]
+}
@@ -0,0 +1,161 @@
+package delay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/probably-not/q/micro"
+)
+
+func TestSchedule(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		delays []time.Duration
+		order  []int
+	}{
+		{
+			desc:   "Jobs are popped in deadline order",
+			delays: []time.Duration{30 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond},
+			order:  []int{1, 2, 0},
+		},
+		{
+			desc:   "Jobs without a delay are popped right away",
+			delays: []time.Duration{20 * time.Millisecond, 0, -time.Millisecond},
+			order:  []int{1, 2, 0},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q := New[int](6, time.Millisecond)
+			defer q.Close()
+
+			scheduled := time.Now()
+			for i, d := range tC.delays {
+				if err := q.Schedule(i, d); err != nil {
+					subT.Fatalf("unexpected error scheduling job %d: %v", i, err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+
+			for i, expected := range tC.order {
+				v, err := q.PopCtx(ctx)
+				if err != nil {
+					subT.Fatalf("unexpected error at pop number %d: %v", i, err)
+				}
+
+				if expected != v {
+					subT.Errorf("expected pop number %d to be job %d, got %d", i, expected, v)
+				}
+
+				if elapsed := time.Since(scheduled); elapsed < tC.delays[v] {
+					subT.Errorf("expected job %d to be popped after %v, got %v", v, tC.delays[v], elapsed)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleFullRing(t *testing.T) {
+	q := New[int](1, time.Millisecond)
+	defer q.Close()
+
+	// The ring holds a single job, so the rest wait for room in order
+	for i := 0; i < 3; i++ {
+		if err := q.Schedule(i, 0); err != nil {
+			t.Fatalf("unexpected error scheduling job %d: %v", i, err)
+		}
+	}
+
+	if q.Len() != 3 {
+		t.Errorf("expected 3 jobs to be due, got %d", q.Len())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		v, err := q.PopCtx(ctx)
+		if err != nil || v != i {
+			t.Errorf("expected pop number %d to be job %d, got %d with error %v", i, i, v, err)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	q := New[int](6, time.Millisecond)
+	q.Schedule(1, 0)
+	q.Schedule(2, time.Hour)
+
+	if q.Scheduled() != 1 {
+		t.Errorf("expected 1 job to be scheduled, got %d", q.Scheduled())
+	}
+
+	q.Close()
+	q.Close() // Closing twice is a no-op
+
+	if err := q.Schedule(3, 0); !errors.Is(err, micro.ErrClosed) {
+		t.Errorf("expected the schedule error to be %v, got %v", micro.ErrClosed, err)
+	}
+
+	if v, ok := q.TryPop(); !ok || v != 1 {
+		t.Errorf("expected the due job to be drained after closing, got %d (ok %t)", v, ok)
+	}
+
+	if _, err := q.PopCtx(context.Background()); !errors.Is(err, micro.ErrClosed) {
+		t.Errorf("expected the pop error to be %v, got %v", micro.ErrClosed, err)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	q := New[int](4, time.Millisecond)
+	defer q.Close()
+
+	const jobs = int32(200)
+	var wg sync.WaitGroup
+	for s := 0; s < 4; s++ {
+		wg.Add(1)
+
+		go func(s int) {
+			defer wg.Done()
+
+			for i := 0; i < int(jobs)/4; i++ {
+				q.Schedule(i, time.Duration(i%10)*time.Millisecond)
+			}
+		}(s)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	popped := int32(0)
+	for c := 0; c < 4; c++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// The consumer that pops the last job cancels the others, so an error before that is a timeout
+			for {
+				if _, err := q.PopCtx(ctx); err != nil {
+					return
+				}
+
+				if atomic.AddInt32(&popped, 1) == jobs {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if popped != jobs {
+		t.Errorf("expected %d jobs to be popped before the timeout, got %d", jobs, popped)
+	}
+}
@@ -0,0 +1,88 @@
+package delay
+
+const (
+	wheelBits   = 6
+	wheelSlots  = 1 << wheelBits
+	wheelMask   = wheelSlots - 1
+	wheelLevels = 4
+	// wheelSpan is the number of ticks that the wheel covers. Jobs that are further away are kept in the furthest
+	// slot of the top level, and are placed again when that slot is cascaded.
+	wheelSpan = 1 << (wheelBits * wheelLevels)
+)
+
+type entry[T any] struct {
+	v   T
+	due uint64
+}
+
+// wheel is a hierarchical timer wheel, counted in ticks. Level l holds the jobs that are due between
+// 64^l and 64^(l+1) ticks away, in the slot of their due tick at that level. When the wheel enters the span
+// of a slot of a higher level, the jobs in it are cascaded down to the levels below.
+// The wheel is not safe for concurrent use.
+type wheel[T any] struct {
+	levels  [wheelLevels][wheelSlots][]entry[T]
+	current uint64
+	size    int
+}
+
+// insert schedules the job at the due tick. It returns false if the job is already due, without scheduling it.
+func (w *wheel[T]) insert(v T, due uint64) bool {
+	if due <= w.current {
+		return false
+	}
+
+	w.place(entry[T]{v: v, due: due})
+	w.size++
+	return true
+}
+
+func (w *wheel[T]) place(e entry[T]) {
+	delta := e.due - w.current
+	at := e.due
+	if delta >= wheelSpan {
+		at = w.current + wheelSpan - 1
+		delta = wheelSpan - 1
+	}
+
+	level := 0
+	for delta >= 1<<(wheelBits*(level+1)) {
+		level++
+	}
+
+	slot := (at >> (wheelBits * level)) & wheelMask
+	w.levels[level][slot] = append(w.levels[level][slot], e)
+}
+
+// advance moves the wheel forward to the tick, calling due with every job that comes due, in deadline order.
+func (w *wheel[T]) advance(to uint64, due func(v T)) {
+	for w.current < to {
+		w.current++
+
+		// Higher levels are cascaded first, so that their jobs are in place before the lower levels are visited
+		for level := wheelLevels - 1; level > 0; level-- {
+			if w.current&(1<<(wheelBits*level)-1) != 0 {
+				continue
+			}
+
+			slot := (w.current >> (wheelBits * level)) & wheelMask
+			entries := w.levels[level][slot]
+			w.levels[level][slot] = nil
+			for _, e := range entries {
+				if e.due <= w.current {
+					w.size--
+					due(e.v)
+					continue
+				}
+				w.place(e)
+			}
+		}
+
+		slot := w.current & wheelMask
+		entries := w.levels[0][slot]
+		w.levels[0][slot] = nil
+		for _, e := range entries {
+			w.size--
+			due(e.v)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package delay
+
+import "testing"
+
+func TestWheel(t *testing.T) {
+	testCases := []struct {
+		desc string
+		due  []uint64
+	}{
+		{
+			desc: "Jobs on the lowest level come due on their tick",
+			due:  []uint64{1, 2, 63},
+		},
+		{
+			desc: "Jobs are cascaded from the higher levels on the boundaries of their slots",
+			due:  []uint64{64, 65, 127, 4095, 4096, 4097, 262144, 262145, 300000},
+		},
+		{
+			desc: "Jobs beyond the span of the wheel are placed again until they come due",
+			due:  []uint64{wheelSpan - 1, wheelSpan, wheelSpan + 5, 2*wheelSpan + 1},
+		},
+		{
+			desc: "Jobs that are inserted out of order come due in deadline order",
+			due:  []uint64{4097, 3, 64, 1},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			w := &wheel[uint64]{}
+			last := uint64(0)
+			for _, due := range tC.due {
+				if !w.insert(due, due) {
+					subT.Errorf("expected job due at %d to be scheduled", due)
+				}
+				if due > last {
+					last = due
+				}
+			}
+
+			fired := 0
+			prev := uint64(0)
+			w.advance(last, func(due uint64) {
+				if due != w.current {
+					subT.Errorf("expected job due at %d to come due on its tick, got tick %d", due, w.current)
+				}
+
+				if due < prev {
+					subT.Errorf("expected job due at %d to come due after job due at %d", due, prev)
+				}
+				prev = due
+				fired++
+			})
+
+			if fired != len(tC.due) || w.size != 0 {
+				subT.Errorf("expected %d jobs to come due and none to be left, got %d due and %d left", len(tC.due), fired, w.size)
+			}
+		})
+	}
+}
+
+func TestWheelInsertDue(t *testing.T) {
+	w := &wheel[int]{}
+	w.advance(10, func(int) {})
+
+	if w.insert(1, 10) {
+		t.Errorf("expected a job that is already due not to be scheduled")
+	}
+
+	if !w.insert(1, 11) || w.size != 1 {
+		t.Errorf("expected a job due on the next tick to be scheduled, got a size of %d", w.size)
+	}
+}
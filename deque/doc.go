@@ -0,0 +1,14 @@
+// Package deque contains a work-stealing deque in the style of Chase and Lev, built on the same packed state
+// as the queues in this module: the bottom of the deque is kept in the low 16 bits of a `uint32`, and the top in
+// the high 16 bits, with the same overflow protection for the bottom as the head of the queues.
+// The deque has a single owner, which pushes to and pops from the bottom, like a stack, and any number of thieves,
+// which steal from the top, like the consumers of a `nano.Q`. This is the shape of the local queue of a worker
+// in a work-stealing scheduler: the owner works on its most recent tasks while they are still in its cache,
+// and idle workers steal the oldest tasks.
+// Since the top and bottom share a single word, popping from the bottom claims the position with a compare and swap,
+// which also makes the owner and the thieves agree on who gets the last task without the fences of the original design.
+// As in the original design, the owner takes the last task by moving the top forward, so that popping it and pushing
+// a new task can't bring the word back to a value that a thief saved before the pop.
+// Like a `nano.Q`, the slice of tasks is managed by an outside source, and a thief that fails its commit may read
+// a task while the owner is writing to its position, so access to the slice of tasks must be threadsafe.
+package deque
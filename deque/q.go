@@ -0,0 +1,163 @@
+package deque
+
+import (
+	"sync/atomic"
+
+	"github.com/probably-not/q/internal/check"
+	"github.com/probably-not/q/internal/consts"
+)
+
+var (
+	// ErrFactorTooLarge is returned when the size factor does not fit in the halves of the deque state.
+	ErrFactorTooLarge = check.ErrFactorTooLarge
+	// ErrFactorZero is returned when the size factor is zero or negative.
+	ErrFactorZero = check.ErrFactorZero
+)
+
+type Q uint32
+
+func NewQ() Q {
+	return 0
+}
+
+// NewQChecked creates a new deque after validating the size factor that will be passed to its operations.
+// If the factor can't be used with the deque, ErrFactorZero or ErrFactorTooLarge is returned.
+func NewQChecked(factor int) (Q, error) {
+	if err := check.FactorU32(factor); err != nil {
+		return 0, err
+	}
+
+	return NewQ(), nil
+}
+
+// PushBottom will calculate the position that can currently be pushed to at the bottom of the deque.
+// It returns the position, along with a boolean indicating if the deque is full or not.
+// If the deque is full, `-1, true` will be returned.
+// If the deque is not full, `pos, false` will be returned.
+// PushBottom may only be called by the owner of the deque.
+func (q *Q) PushBottom(factor int) (int, bool) {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+	bottom := acquired & mask
+	top := acquired >> 16 & mask
+	next := (bottom + uint32(1)) & mask
+
+	if acquired&consts.PushOverflowCheckU32 != 0 {
+		atomic.AddUint32((*uint32)(q), consts.PushOverflowProtectionU32)
+	}
+
+	if next == top {
+		return -1, true
+	}
+
+	return int(bottom), false
+}
+
+// PushBottomCommit will commit the previously executed PushBottom operation to the deque.
+// This moves the bottom of the deque to the next push-able position.
+func (q *Q) PushBottomCommit() {
+	atomic.AddUint32((*uint32)(q), 1)
+}
+
+// PopBottom will claim the position at the bottom of the deque, which is the position that was pushed last.
+// It returns the position, along with a boolean indicating if the deque is empty or not.
+// If the deque is empty, `-1, true` will be returned.
+// If the deque is not empty, `pos, false` will be returned, and the position belongs to the owner.
+// Unlike Steal, there is no commit: the position is claimed with a compare and swap that is retried
+// while thieves steal from the top, and once it is claimed no thief can commit a steal of it.
+// The last task in the deque is claimed by moving the top forward, as a steal does, rather than by moving the bottom
+// back, so that a following PushBottom can never return the state to a savepoint that a thief is still holding.
+// PopBottom may only be called by the owner of the deque.
+func (q *Q) PopBottom(factor int) (int, bool) {
+	check.AssertFactorU32(factor)
+	mask := (uint32(1) << factor) - 1
+	for {
+		acquired := atomic.LoadUint32((*uint32)(q))
+		bottom := acquired & mask
+		top := acquired >> 16 & mask
+
+		if bottom == top {
+			return -1, true
+		}
+
+		// The last task is the one that the thieves are racing for, so it is taken from the top
+		if (bottom-top)&mask == 1 {
+			if atomic.CompareAndSwapUint32((*uint32)(q), acquired, uint32(acquired+consts.CommitPopU32)) {
+				return int(top), false
+			}
+			continue
+		}
+
+		// Moving the bottom back from zero would borrow from the top, so it is moved back from the top of the
+		// range that is protected from overflowing instead, which is the same position since the size divides it
+		next := acquired - 1
+		if uint16(acquired) == 0 {
+			next = acquired + (consts.PushOverflowCheckU32 - 1)
+		}
+
+		if atomic.CompareAndSwapUint32((*uint32)(q), acquired, next) {
+			return int((bottom - 1) & mask), false
+		}
+	}
+}
+
+// Steal will calculate the position that can currently be stolen from the top of the deque,
+// which is the position that was pushed first.
+// It returns the position, a save point (to allow ensuring the commit),
+// along with a boolean indicating if the deque is empty or not.
+// If the deque is empty, `-1, 0, true` will be returned.
+// If the deque is not empty, `pos, savepoint, false` will be returned.
+// After receiving the position and savepoint, StealCommit must be called in
+// order to ensure that the task is truly the thief's task, and it has not been
+// stolen by another thief or popped by the owner.
+func (q *Q) Steal(factor int) (int, uint32, bool) {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+	bottom := acquired & mask
+	top := acquired >> 16 & mask
+
+	if bottom == top {
+		return -1, 0, true
+	}
+
+	return int(top), acquired, false
+}
+
+// StealCommit will commit the previously executed Steal operation to the deque.
+// This moves the top of the deque to the next steal-able position.
+// It requires a savepoint that was returned by the Steal operation, which will
+// be used to ensure that the operation is in fact atomic.
+// If the commit succeeds, `true` is returned to indicate that the caller does in fact
+// have the task that it received in the Steal operation.
+func (q *Q) StealCommit(savepoint uint32) bool {
+	return atomic.CompareAndSwapUint32((*uint32)(q), savepoint, uint32(savepoint+consts.CommitPopU32))
+}
+
+// Len returns the number of tasks that are committed to the deque and not yet popped or stolen.
+func (q *Q) Len(factor int) int {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+	bottom := acquired & mask
+	top := acquired >> 16 & mask
+
+	return int((bottom - top) & mask)
+}
+
+// Cap returns the number of tasks that the deque can hold, which is one less than its number of positions,
+// since a full deque always keeps one position free to tell it apart from an empty deque.
+func (q *Q) Cap(factor int) int {
+	check.AssertFactorU32(factor)
+	return (1 << factor) - 1
+}
+
+// IsEmpty reports whether the deque is empty, from a single atomic load and without side effects.
+func (q *Q) IsEmpty(factor int) bool {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+
+	return acquired&mask == acquired>>16&mask
+}
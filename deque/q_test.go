@@ -0,0 +1,222 @@
+package deque
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOwner(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		expectedPops []int
+		factor       int
+		pushes       int
+		state        Q
+	}{
+		{
+			desc:         "Owner pops the positions it pushed last first",
+			factor:       6,
+			pushes:       3,
+			expectedPops: []int{2, 1, 0},
+			state:        0,
+		},
+		{
+			desc:         "Owner pops positions that wrap around the deque",
+			factor:       2,
+			pushes:       3,
+			expectedPops: []int{0, 3, 2},
+			state:        0x00020002,
+		},
+		{
+			desc:         "Owner pops across the overflow protection of the bottom",
+			factor:       2,
+			pushes:       2,
+			expectedPops: []int{0, 3},
+			state:        0x7fff7fff,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q := tC.state
+			top := uint32(q) >> 16
+			for i := 0; i < tC.pushes; i++ {
+				if _, isFull := q.PushBottom(tC.factor); isFull {
+					subT.Errorf("unexpected full deque at push number %d", i)
+					return
+				}
+				q.PushBottomCommit()
+			}
+
+			for i, expected := range tC.expectedPops {
+				pos, isEmpty := q.PopBottom(tC.factor)
+				if isEmpty || expected != pos {
+					subT.Errorf("expected pop number %d to be %d, got %d (empty %t)", i, expected, pos, isEmpty)
+				}
+			}
+
+			if pos, isEmpty := q.PopBottom(tC.factor); !isEmpty || pos != -1 {
+				subT.Errorf("expected a pop from an empty deque to return -1 and true, got %d and %t", pos, isEmpty)
+			}
+
+			// Only the last pop moves the top, since the last task is taken from the top
+			if expected := (top + 1) & 0xffff; uint32(q)>>16 != expected {
+				subT.Errorf("expected the top to be %#x, got %#x", expected, uint32(q)>>16)
+			}
+		})
+	}
+}
+
+func TestSteal(t *testing.T) {
+	q := NewQ()
+	const factor = 2
+	for i := 0; i < 3; i++ {
+		q.PushBottom(factor)
+		q.PushBottomCommit()
+	}
+
+	if _, isFull := q.PushBottom(factor); !isFull {
+		t.Errorf("expected a push to a full deque to be rejected")
+	}
+
+	pos, savepoint, isEmpty := q.Steal(factor)
+	if isEmpty || pos != 0 {
+		t.Errorf("expected the thief to steal the position pushed first, got %d (empty %t)", pos, isEmpty)
+	}
+
+	if bottom, _ := q.PopBottom(factor); bottom != 2 {
+		t.Errorf("expected the owner to pop the position pushed last, got %d", bottom)
+	}
+
+	// The owner popping from the bottom changes the state, so the steal has to be retried
+	if q.StealCommit(savepoint) {
+		t.Errorf("expected a steal commit with a stale savepoint to fail")
+	}
+
+	pos, savepoint, _ = q.Steal(factor)
+	if pos != 0 || !q.StealCommit(savepoint) {
+		t.Errorf("expected the retried steal of position 0 to commit, got position %d", pos)
+	}
+
+	if bottom, _ := q.PopBottom(factor); bottom != 1 {
+		t.Errorf("expected the owner to pop the last position 1, got %d", bottom)
+	}
+
+	if _, _, isEmpty := q.Steal(factor); !isEmpty || q.Len(factor) != 0 {
+		t.Errorf("expected the deque to be empty, got a length of %d", q.Len(factor))
+	}
+}
+
+func TestStealAfterOwnerPopsAndPushes(t *testing.T) {
+	q := NewQ()
+	const factor = 2
+	tasks := [1 << factor]string{}
+
+	pos, _ := q.PushBottom(factor)
+	tasks[pos] = "X"
+	q.PushBottomCommit()
+
+	// The thief reads the only task, and is descheduled before its commit
+	stolen, savepoint, isEmpty := q.Steal(factor)
+	if isEmpty {
+		t.Fatalf("expected the thief to find a task")
+	}
+	thief := tasks[stolen]
+
+	// The owner pops the same task, and pushes a new one to the bottom
+	pos, isEmpty = q.PopBottom(factor)
+	if isEmpty || tasks[pos] != "X" {
+		t.Fatalf("expected the owner to pop X, got %q (empty %t)", tasks[pos], isEmpty)
+	}
+
+	pos, _ = q.PushBottom(factor)
+	tasks[pos] = "Y"
+	q.PushBottomCommit()
+
+	// The thief's savepoint is stale, so X isn't delivered twice, and Y isn't lost
+	if q.StealCommit(savepoint) {
+		t.Errorf("expected the thief's commit of %q to fail after the owner popped it", thief)
+	}
+
+	pos, savepoint, _ = q.Steal(factor)
+	if !q.StealCommit(savepoint) || tasks[pos] != "Y" {
+		t.Errorf("expected the retried steal to commit Y, got %q", tasks[pos])
+	}
+
+	if q.Len(factor) != 0 {
+		t.Errorf("expected the deque to be empty, got a length of %d", q.Len(factor))
+	}
+}
+
+func TestConcurrentSteal(t *testing.T) {
+	q := NewQ()
+	const factor = 4
+	const availableSlots = 1 << factor
+	const tasks = 10000
+	var slots [availableSlots]int64
+	var taken [tasks]int32
+	completedProducing := int32(0)
+
+	var wg sync.WaitGroup
+	wg.Add(1) // Add owner goroutine
+
+	// Owner, which pushes every task and pops some of them back from the bottom
+	go func() {
+		defer wg.Done()
+		defer atomic.StoreInt32(&completedProducing, 1)
+
+		for i := int64(0); i < tasks; i++ {
+			pos, isFull := q.PushBottom(factor)
+			if isFull {
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				i--
+				continue
+			}
+
+			atomic.StoreInt64(&slots[pos], i)
+			q.PushBottomCommit()
+
+			if i%3 == 0 {
+				if pos, isEmpty := q.PopBottom(factor); !isEmpty {
+					atomic.AddInt32(&taken[atomic.LoadInt64(&slots[pos])], 1)
+				}
+			}
+		}
+	}()
+
+	// Thieves
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				pos, savepoint, isEmpty := q.Steal(factor)
+				if isEmpty {
+					if atomic.LoadInt32(&completedProducing) == 1 && q.IsEmpty(factor) {
+						break
+					}
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					continue
+				}
+
+				task := atomic.LoadInt64(&slots[pos])
+				if !q.StealCommit(savepoint) {
+					continue // Commit failed so we can't run the task
+				}
+
+				atomic.AddInt32(&taken[task], 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for task, n := range taken {
+		if n != 1 {
+			t.Errorf("expected task %d to be taken once, got %d", task, n)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package durable contains a ring of byte records that is persisted to a file, so that the records that were
+// buffered in it survive a restart of the process.
+// The ring keeps its records in memory in a `micro.Ring`, and writes through to the file: every pushed record
+// is written to its slot in the file before it is committed, and the number of popped records is checkpointed in the
+// header of the file. The file is synced either after every commit, or at a configurable interval by a background
+// goroutine, trading the records that can be lost on a crash of the machine for throughput.
+// Like `micro.Q`, the ring is safe for a single producer with multiple consumers.
+//
+// Every slot holds the sequence number of its record and a checksum, so that the head of the ring is recovered
+// from the slots themselves rather than from a checkpoint. On Open, the ring is recovered with the following policy:
+//
+//   - The newest slot with a valid checksum is the last record of the ring, even if its push was reserved and written
+//     but never committed. Records are delivered at least once, so a record that made it to the file is replayed.
+//   - The ring extends back from that record over consecutive sequence numbers, and stops at the first slot that was
+//     overwritten, torn, or never synced, or at the checkpointed tail. Records before a gap are dropped.
+//   - Records that were popped after the last checkpoint of the tail are delivered again.
+//
+// After a crash of the process, the operating system still holds every write, so only the pops since the last
+// checkpoint are replayed. After a crash of the machine, writes since the last sync may be lost as well.
+package durable
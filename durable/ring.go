@@ -0,0 +1,363 @@
+package durable
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/probably-not/q/internal/check"
+	"github.com/probably-not/q/micro"
+)
+
+const (
+	// Magic is the first word of every ring file.
+	Magic = 0x52554451 // "QDUR" in little endian
+	// Version is the version of the file layout that this package reads and writes.
+	Version = 1
+)
+
+const (
+	magicOffset    = 0
+	versionOffset  = 4
+	factorOffset   = 8
+	slotSizeOffset = 12
+	tailOffset     = 16
+	headerSize     = 64
+	// Every slot starts with the sequence number, the length and the checksum of its record.
+	seqOffset    = 0
+	lengthOffset = 8
+	crcOffset    = 12
+	recordOffset = 16
+)
+
+var (
+	// ErrFactorTooLarge is returned when the size factor does not fit in the halves of the queue state.
+	ErrFactorTooLarge = check.ErrFactorTooLarge
+	// ErrFactorZero is returned when the size factor is zero or negative.
+	ErrFactorZero = check.ErrFactorZero
+	// ErrSlotSize is returned by Open when the slot size is zero or negative.
+	ErrSlotSize = errors.New("slot size must be positive")
+	// ErrInvalidHeader is returned by Open when the file is not a ring file, or was written with a different layout.
+	ErrInvalidHeader = errors.New("file is not a durable ring")
+	// ErrMismatch is returned by Open when the file holds a ring with a different size factor or slot size.
+	ErrMismatch = errors.New("file holds a ring with a different size factor or slot size")
+	// ErrRecordTooLarge is returned by Push when the record does not fit in a slot.
+	ErrRecordTooLarge = errors.New("record is larger than the slot size")
+	// ErrFull is returned by Push when the ring is full.
+	ErrFull = errors.New("ring is full")
+	// ErrEmpty is returned by Pop when the ring is empty.
+	ErrEmpty = errors.New("ring is empty")
+)
+
+// Options configure how often a Ring syncs its file.
+type Options struct {
+	// SyncInterval is the interval at which the file is synced by a background goroutine.
+	// If it is zero, the file is synced on every push and every pop, before they return.
+	SyncInterval time.Duration
+}
+
+// Ring is a ring of byte records that is persisted to a file.
+type Ring struct {
+	syncErr  error
+	closeErr error
+	file     *os.File
+	// ring holds a copy of the record of every slot in memory.
+	ring *micro.Ring[[]byte]
+	done chan struct{}
+	// popped is allocated on its own, which keeps it 64 bit aligned for atomic operations on 32 bit platforms.
+	popped    *uint64
+	pushed    uint64
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	mu        sync.Mutex
+	interval  time.Duration
+	factor    int
+	slotSize  int
+	stride    int
+}
+
+// Open opens the ring file at the path, recovering the records in it, or creates it if it doesn't exist,
+// with `1<<factor` slots that each hold a record of up to slotSize bytes.
+// It returns ErrMismatch if the file holds a ring with a different factor or slot size,
+// and ErrInvalidHeader if the file is not a ring file.
+func Open(path string, factor, slotSize int, opts Options) (*Ring, error) {
+	if err := check.FactorU32(factor); err != nil {
+		return nil, err
+	}
+
+	if slotSize <= 0 {
+		return nil, ErrSlotSize
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Ring{
+		file:     f,
+		ring:     micro.NewRing[[]byte](factor),
+		done:     make(chan struct{}),
+		popped:   new(uint64),
+		interval: opts.SyncInterval,
+		factor:   factor,
+		slotSize: slotSize,
+		stride:   (recordOffset + slotSize + 7) &^ 7,
+	}
+
+	if err := r.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if r.interval > 0 {
+		r.wg.Add(1)
+		go r.syncEvery(r.interval)
+	}
+
+	return r, nil
+}
+
+// Push writes the record to the next slot of the ring, and commits it.
+// It returns ErrFull if the ring is full, or ErrRecordTooLarge if the record does not fit in a slot.
+// If the ring syncs on every operation, the record is synced to the file before it is committed.
+func (r *Ring) Push(record []byte) error {
+	if len(record) > r.slotSize {
+		return ErrRecordTooLarge
+	}
+
+	// With a single producer, the ring can't fill up between this check and the push below,
+	// and the position of the push is always the sequence number of the record.
+	if r.ring.IsFull() {
+		return ErrFull
+	}
+
+	seq := r.pushed
+	if err := r.writeSlot(int(seq&uint64(r.ring.Cap())), seq, record); err != nil {
+		return err
+	}
+
+	if r.interval == 0 {
+		if err := r.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	r.ring.TryPush(append([]byte(nil), record...))
+	r.pushed++
+	return nil
+}
+
+// Pop appends the next record of the ring to dst, and returns the extended slice.
+// It returns ErrEmpty if the ring is empty.
+// If the ring syncs on every operation, the tail is checkpointed and synced before Pop returns,
+// and if that sync fails, the popped record is returned along with the error.
+func (r *Ring) Pop(dst []byte) ([]byte, error) {
+	record, ok := r.ring.TryPop()
+	if !ok {
+		return dst, ErrEmpty
+	}
+
+	atomic.AddUint64(r.popped, 1)
+	dst = append(dst, record...)
+	if r.interval == 0 {
+		return dst, r.Sync()
+	}
+
+	return dst, nil
+}
+
+// Len returns the number of records that are committed to the ring and not yet popped.
+func (r *Ring) Len() int {
+	return r.ring.Len()
+}
+
+// Sync checkpoints the tail of the ring, and syncs the file.
+// It returns the first error that a sync has failed with, including the syncs of the background goroutine.
+func (r *Ring) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.syncErr != nil {
+		return r.syncErr
+	}
+
+	var tail [8]byte
+	binary.LittleEndian.PutUint64(tail[:], atomic.LoadUint64(r.popped))
+	if _, err := r.file.WriteAt(tail[:], tailOffset); err != nil {
+		r.syncErr = err
+		return err
+	}
+
+	if err := r.file.Sync(); err != nil {
+		r.syncErr = err
+		return err
+	}
+
+	return nil
+}
+
+// Close stops the background syncs, syncs the file a final time, and closes it.
+// The ring must not be used after it is closed. Closing more than once is a no-op,
+// which returns the error of the first Close.
+func (r *Ring) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+
+		r.closeErr = r.Sync()
+		if err := r.file.Close(); r.closeErr == nil {
+			r.closeErr = err
+		}
+	})
+
+	return r.closeErr
+}
+
+func (r *Ring) syncEvery(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.Sync() // A failed sync is kept, and returned by the next call to Sync or Close
+		}
+	}
+}
+
+func (r *Ring) writeSlot(pos int, seq uint64, record []byte) error {
+	slot := make([]byte, recordOffset+len(record))
+	binary.LittleEndian.PutUint64(slot[seqOffset:], seq)
+	binary.LittleEndian.PutUint32(slot[lengthOffset:], uint32(len(record)))
+	copy(slot[recordOffset:], record)
+	binary.LittleEndian.PutUint32(slot[crcOffset:], checksum(slot))
+
+	_, err := r.file.WriteAt(slot, int64(headerSize+pos*r.stride))
+	return err
+}
+
+// load initializes a new ring file, or recovers the ring from an existing one according to the replay policy.
+func (r *Ring) load() error {
+	size := int64(headerSize + (1<<r.factor)*r.stride)
+	info, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() == 0 {
+		return r.initialize(size)
+	}
+
+	file := make([]byte, size)
+	if _, err := r.file.ReadAt(file[:headerSize], 0); err != nil {
+		return ErrInvalidHeader
+	}
+
+	if binary.LittleEndian.Uint32(file[magicOffset:]) != Magic || binary.LittleEndian.Uint32(file[versionOffset:]) != Version {
+		return ErrInvalidHeader
+	}
+
+	if int(binary.LittleEndian.Uint32(file[factorOffset:])) != r.factor || int(binary.LittleEndian.Uint32(file[slotSizeOffset:])) != r.slotSize {
+		return ErrMismatch
+	}
+
+	if _, err := r.file.ReadAt(file[headerSize:], headerSize); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	r.recover(file, binary.LittleEndian.Uint64(file[tailOffset:]))
+	return nil
+}
+
+func (r *Ring) initialize(size int64) error {
+	if err := r.file.Truncate(size); err != nil {
+		return err
+	}
+
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(header[magicOffset:], Magic)
+	binary.LittleEndian.PutUint32(header[versionOffset:], Version)
+	binary.LittleEndian.PutUint32(header[factorOffset:], uint32(r.factor))
+	binary.LittleEndian.PutUint32(header[slotSizeOffset:], uint32(r.slotSize))
+	if _, err := r.file.WriteAt(header, 0); err != nil {
+		return err
+	}
+
+	return r.file.Sync()
+}
+
+// recover rebuilds the ring from the slots of the file, starting at the newest valid record
+// and extending back over consecutive sequence numbers, down to the checkpointed tail.
+func (r *Ring) recover(file []byte, checkpoint uint64) {
+	mask := uint64(1<<r.factor - 1)
+	records := make([][]byte, 1<<r.factor)
+	seqs := make([]uint64, 1<<r.factor)
+	newest, found := uint64(0), false
+	for pos := range records {
+		seq, record, ok := r.readSlot(file, pos)
+		if !ok || seq < checkpoint {
+			continue
+		}
+
+		seqs[pos], records[pos] = seq, record
+		if !found || seq > newest {
+			newest, found = seq, true
+		}
+	}
+
+	head, tail := checkpoint, checkpoint
+	if found {
+		head, tail = newest+1, newest+1
+		for tail > checkpoint && head-tail < mask {
+			pos := (tail - 1) & mask
+			if records[pos] == nil || seqs[pos] != tail-1 {
+				break
+			}
+			tail--
+		}
+	}
+
+	// The queue is moved to the position of the tail, so that the positions of the slots match their sequence numbers
+	for i := uint64(0); i < tail&mask; i++ {
+		r.ring.TryPush(nil)
+		r.ring.TryPop()
+	}
+
+	for seq := tail; seq < head; seq++ {
+		r.ring.TryPush(records[seq&mask])
+	}
+
+	r.pushed = head
+	*r.popped = tail
+}
+
+func (r *Ring) readSlot(file []byte, pos int) (uint64, []byte, bool) {
+	slot := file[headerSize+pos*r.stride : headerSize+(pos+1)*r.stride]
+	n := int(binary.LittleEndian.Uint32(slot[lengthOffset:]))
+	if n > r.slotSize {
+		return 0, nil, false
+	}
+
+	slot = slot[:recordOffset+n]
+	if binary.LittleEndian.Uint32(slot[crcOffset:]) != checksum(slot) {
+		return 0, nil, false
+	}
+
+	return binary.LittleEndian.Uint64(slot[seqOffset:]), append([]byte{}, slot[recordOffset:]...), true
+}
+
+// checksum returns the checksum of a slot, covering everything but the checksum itself.
+func checksum(slot []byte) uint32 {
+	crc := crc32.ChecksumIEEE(slot[:crcOffset])
+	return crc32.Update(crc, crc32.IEEETable, slot[recordOffset:])
+}
@@ -0,0 +1,354 @@
+package durable
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// crash abandons the ring without a final sync, as if the process had been killed.
+func crash(r *Ring) {
+	close(r.done)
+	r.wg.Wait()
+	r.file.Close()
+}
+
+func records(n int) [][]byte {
+	rs := make([][]byte, n)
+	for i := range rs {
+		rs[i] = []byte(fmt.Sprintf("record-%d", i))
+	}
+	return rs
+}
+
+func TestPushPop(t *testing.T) {
+	testCases := []struct {
+		expectedErr error
+		desc        string
+		records     [][]byte
+	}{
+		{
+			desc:        "Records are popped in the order they were pushed",
+			records:     records(3),
+			expectedErr: nil,
+		},
+		{
+			desc:        "Records larger than a slot are rejected",
+			records:     [][]byte{[]byte("record-that-is-too-large")},
+			expectedErr: ErrRecordTooLarge,
+		},
+		{
+			desc:        "Pushes to a full ring are rejected",
+			records:     records(4),
+			expectedErr: ErrFull,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			r, err := Open(filepath.Join(subT.TempDir(), "ring"), 2, 16, Options{})
+			if err != nil {
+				subT.Fatalf("unexpected error opening the ring %v", err)
+			}
+			defer r.Close()
+
+			var pushed [][]byte
+			for _, record := range tC.records {
+				if err = r.Push(record); err != nil {
+					break
+				}
+				pushed = append(pushed, record)
+			}
+
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the push error to be %v, got %v", tC.expectedErr, err)
+			}
+
+			for i, expected := range pushed {
+				record, err := r.Pop(nil)
+				if err != nil {
+					subT.Errorf("unexpected error at pop number %d: %v", i, err)
+					return
+				}
+
+				if !bytes.Equal(expected, record) {
+					subT.Errorf("expected pop number %d to be %q, got %q", i, expected, record)
+				}
+			}
+
+			if _, err := r.Pop(nil); !errors.Is(err, ErrEmpty) {
+				subT.Errorf("expected the pop error on an empty ring to be %v, got %v", ErrEmpty, err)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	testCases := []struct {
+		// before runs on the ring before it is abandoned, and returns the records that are expected after recovery.
+		before   func(subT *testing.T, r *Ring) [][]byte
+		corrupt  func(file []byte)
+		desc     string
+		interval time.Duration
+		graceful bool
+	}{
+		{
+			desc: "Records left after a graceful close are recovered",
+			before: func(subT *testing.T, r *Ring) [][]byte {
+				pushAll(subT, r, records(5))
+				popN(subT, r, 2)
+				return records(5)[2:]
+			},
+			interval: 0,
+			graceful: true,
+		},
+		{
+			desc: "Records left after a crash are recovered when every operation is synced",
+			before: func(subT *testing.T, r *Ring) [][]byte {
+				pushAll(subT, r, records(5))
+				popN(subT, r, 2)
+				return records(5)[2:]
+			},
+			interval: 0,
+			graceful: false,
+		},
+		{
+			desc: "Records popped since the last checkpoint are replayed after a crash",
+			before: func(subT *testing.T, r *Ring) [][]byte {
+				pushAll(subT, r, records(5))
+				popN(subT, r, 2)
+				return records(5)
+			},
+			interval: time.Hour,
+			graceful: false,
+		},
+		{
+			desc: "Records popped before a background checkpoint are not replayed after a crash",
+			before: func(subT *testing.T, r *Ring) [][]byte {
+				pushAll(subT, r, records(5))
+				popN(subT, r, 2)
+				<-time.After(50 * time.Millisecond) // Allow the background goroutine to checkpoint the tail
+				return records(5)[2:]
+			},
+			interval: 1 * time.Millisecond,
+			graceful: false,
+		},
+		{
+			desc: "Record that was written but never committed is replayed",
+			before: func(subT *testing.T, r *Ring) [][]byte {
+				pushAll(subT, r, records(2))
+				pos, _ := r.ring.Push()
+				if err := r.writeSlot(pos, r.pushed, []byte("reserved")); err != nil {
+					subT.Fatalf("unexpected error writing the slot %v", err)
+				}
+				return append(records(2), []byte("reserved"))
+			},
+			interval: 0,
+			graceful: false,
+		},
+		{
+			desc: "Torn record is dropped",
+			before: func(subT *testing.T, r *Ring) [][]byte {
+				pushAll(subT, r, records(3))
+				return records(2)
+			},
+			corrupt: func(file []byte) {
+				file[headerSize+2*32+recordOffset] ^= 0xff // The data of the third record, in slots of 32 bytes
+			},
+			interval: 0,
+			graceful: false,
+		},
+		{
+			desc: "Records that wrapped around the ring are recovered in order",
+			before: func(subT *testing.T, r *Ring) [][]byte {
+				for i := 0; i < 3; i++ {
+					pushAll(subT, r, records(7))
+					popN(subT, r, 7)
+				}
+				pushAll(subT, r, records(5))
+				popN(subT, r, 1)
+				return records(5)[1:]
+			},
+			interval: 0,
+			graceful: false,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			path := filepath.Join(subT.TempDir(), "ring")
+			r, err := Open(path, 3, 16, Options{SyncInterval: tC.interval})
+			if err != nil {
+				subT.Fatalf("unexpected error opening the ring %v", err)
+			}
+
+			expected := tC.before(subT, r)
+			if tC.graceful {
+				if err := r.Close(); err != nil {
+					subT.Fatalf("unexpected error closing the ring %v", err)
+				}
+			} else {
+				crash(r)
+			}
+
+			if tC.corrupt != nil {
+				file, err := os.ReadFile(path)
+				if err != nil {
+					subT.Fatalf("unexpected error reading the ring file %v", err)
+				}
+				tC.corrupt(file)
+				if err := os.WriteFile(path, file, 0o600); err != nil {
+					subT.Fatalf("unexpected error writing the ring file %v", err)
+				}
+			}
+
+			r, err = Open(path, 3, 16, Options{})
+			if err != nil {
+				subT.Fatalf("unexpected error reopening the ring %v", err)
+			}
+			defer r.Close()
+
+			if r.Len() != len(expected) {
+				subT.Errorf("expected the recovered length to be %d, got %d", len(expected), r.Len())
+			}
+
+			for i, e := range expected {
+				record, err := r.Pop(nil)
+				if err != nil {
+					subT.Errorf("unexpected error at pop number %d: %v", i, err)
+					return
+				}
+
+				if !bytes.Equal(e, record) {
+					subT.Errorf("expected pop number %d to be %q, got %q", i, e, record)
+				}
+			}
+
+			// The recovered ring keeps working from where it left off
+			pushAll(subT, r, records(7))
+			popN(subT, r, 7)
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	testCases := []struct {
+		expectedErr error
+		desc        string
+		factor      int
+		slotSize    int
+	}{
+		{
+			desc:        "Same factor and slot size reopen the ring",
+			factor:      3,
+			slotSize:    16,
+			expectedErr: nil,
+		},
+		{
+			desc:        "Different factor is rejected",
+			factor:      4,
+			slotSize:    16,
+			expectedErr: ErrMismatch,
+		},
+		{
+			desc:        "Different slot size is rejected",
+			factor:      3,
+			slotSize:    32,
+			expectedErr: ErrMismatch,
+		},
+		{
+			desc:        "Zero factor is rejected",
+			factor:      0,
+			slotSize:    16,
+			expectedErr: ErrFactorZero,
+		},
+		{
+			desc:        "Zero slot size is rejected",
+			factor:      3,
+			slotSize:    0,
+			expectedErr: ErrSlotSize,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			path := filepath.Join(subT.TempDir(), "ring")
+			r, err := Open(path, 3, 16, Options{})
+			if err != nil {
+				subT.Fatalf("unexpected error opening the ring %v", err)
+			}
+			r.Close()
+
+			r, err = Open(path, tC.factor, tC.slotSize, Options{})
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+
+			if err == nil {
+				r.Close()
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "ring")
+	if err := os.WriteFile(path, []byte("not a ring"), 0o600); err != nil {
+		t.Fatalf("unexpected error writing the file %v", err)
+	}
+
+	if _, err := Open(path, 3, 16, Options{}); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("expected the returned error to be %v, got %v", ErrInvalidHeader, err)
+	}
+}
+
+func pushAll(t *testing.T, r *Ring, rs [][]byte) {
+	t.Helper()
+	for i, record := range rs {
+		if err := r.Push(record); err != nil {
+			t.Fatalf("unexpected error at push number %d: %v", i, err)
+		}
+	}
+}
+
+func popN(t *testing.T, r *Ring, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := r.Pop(nil); err != nil {
+			t.Fatalf("unexpected error at pop number %d: %v", i, err)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		interval time.Duration
+	}{
+		{
+			desc:     "Closing twice is a no-op when every operation is synced",
+			interval: 0,
+		},
+		{
+			desc:     "Closing twice is a no-op with a background sync",
+			interval: 1 * time.Millisecond,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			path := filepath.Join(subT.TempDir(), "ring")
+			r, err := Open(path, 3, 16, Options{SyncInterval: tC.interval})
+			if err != nil {
+				subT.Fatalf("unexpected error opening the ring %v", err)
+			}
+			pushAll(subT, r, records(2))
+
+			if err := r.Close(); err != nil {
+				subT.Errorf("unexpected error closing the ring %v", err)
+			}
+
+			if err := r.Close(); err != nil {
+				subT.Errorf("unexpected error closing the ring a second time %v", err)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"unsafe"
 
 	"github.com/probably-not/q/micro"
+	"github.com/probably-not/q/milli"
 	"github.com/probably-not/q/nano"
 	"github.com/probably-not/q/pico"
 )
@@ -13,9 +14,19 @@ func main() {
 	picoQ := pico.NewQ()
 	nanoQ := nano.NewQ()
 	microQ := micro.NewQ(6)
+	milliQ := milli.NewQ()
+	pico64Q := pico.NewQ64()
+	nano64Q := nano.NewQ64()
+	micro64Q := micro.NewQ64(20)
+	microPaddedQ := micro.NewPaddedQ(6)
 	fmt.Println("=========================== Queue Memory Sizes ===========================")
 	fmt.Println("PicoQ:", unsafe.Sizeof(picoQ)*8, "bits")
 	fmt.Println("NanoQ:", unsafe.Sizeof(nanoQ)*8, "bits")
 	fmt.Println("MicroQ:", unsafe.Sizeof(microQ)*8, "bits")
+	fmt.Println("MilliQ:", unsafe.Sizeof(*milliQ)*8, "bits")
+	fmt.Println("PicoQ64:", unsafe.Sizeof(pico64Q)*8, "bits")
+	fmt.Println("NanoQ64:", unsafe.Sizeof(nano64Q)*8, "bits")
+	fmt.Println("MicroQ64:", unsafe.Sizeof(micro64Q)*8, "bits")
+	fmt.Println("MicroPaddedQ:", unsafe.Sizeof(*microPaddedQ)*8, "bits")
 	fmt.Println("==========================================================================")
 }
@@ -1,3 +1,3 @@
 module github.com/probably-not/q
 
-go 1.17
+go 1.18
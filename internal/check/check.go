@@ -0,0 +1,68 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxFactorU32 is the largest size factor supported by queues that pack the head and tail into a `uint32`.
+// The head and tail each get 16 bits, and the top bit of the head is reserved for overflow protection.
+const MaxFactorU32 = 15
+
+// MaxFactorU64 is the largest size factor supported by queues that pack the head and tail into a `uint64`.
+// The head and tail each get 32 bits, and the top bit of the head is reserved for overflow protection.
+const MaxFactorU64 = 31
+
+var (
+	ErrFactorTooLarge = errors.New("queue size factor is too large")
+	ErrFactorZero     = errors.New("queue size factor must be greater than zero")
+)
+
+// FactorU32 validates a size factor for a queue that packs the head and tail into a `uint32`.
+func FactorU32(factor int) error {
+	if factor <= 0 {
+		return fmt.Errorf("%w: got %d", ErrFactorZero, factor)
+	}
+
+	if factor > MaxFactorU32 {
+		return fmt.Errorf("%w: got %d, maximum is %d", ErrFactorTooLarge, factor, MaxFactorU32)
+	}
+
+	return nil
+}
+
+// FactorU64 validates a size factor for a queue that packs the head and tail into a `uint64`.
+func FactorU64(factor int) error {
+	if factor <= 0 {
+		return fmt.Errorf("%w: got %d", ErrFactorZero, factor)
+	}
+
+	if factor > MaxFactorU64 {
+		return fmt.Errorf("%w: got %d, maximum is %d", ErrFactorTooLarge, factor, MaxFactorU64)
+	}
+
+	return nil
+}
+
+// AssertFactorU32 panics if the size factor is invalid, but only in builds with the `q_debug` build tag.
+// In regular builds the check compiles away entirely, keeping the Push and Pop operations free of branches.
+func AssertFactorU32(factor int) {
+	if !Debug {
+		return
+	}
+
+	if err := FactorU32(factor); err != nil {
+		panic(err)
+	}
+}
+
+// AssertFactorU64 panics if the size factor is invalid, but only in builds with the `q_debug` build tag.
+func AssertFactorU64(factor int) {
+	if !Debug {
+		return
+	}
+
+	if err := FactorU64(factor); err != nil {
+		panic(err)
+	}
+}
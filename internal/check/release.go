@@ -0,0 +1,6 @@
+//go:build !q_debug
+
+package check
+
+// Debug is true when built with the `q_debug` build tag, enabling assertions inside the queue operations.
+const Debug = false
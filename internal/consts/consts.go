@@ -4,4 +4,11 @@ const (
 	CommitPopU32              = uint32(0x10000)
 	PushOverflowCheckU32      = uint32(0x8000)
 	PushOverflowProtectionU32 = uint32(-0x8000 & 0xffffffff)
+	PushHeadMaskU32           = uint32(0x7fff)
+)
+
+const (
+	CommitPopU64              = uint64(0x100000000)
+	PushOverflowCheckU64      = uint64(0x80000000)
+	PushOverflowProtectionU64 = uint64(-0x80000000 & 0xffffffffffffffff)
 )
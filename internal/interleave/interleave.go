@@ -0,0 +1,197 @@
+// Package interleave is a deterministic scheduler that explores every interleaving of a few goroutines
+// operating on shared atomic state words, in the style of stateless model checkers such as CHESS.
+// Every operation on a Word, and every call to Yield, is a scheduling point where exactly one of the goroutines
+// is allowed to continue. Explore runs the same scenario once for every possible order of those points,
+// so a race that the real scheduler only hits once in a million runs is hit on every test run.
+// The scenarios must be deterministic and bounded: a goroutine that retries forever makes the exploration infinite.
+package interleave
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Word is an atomic 32 bit state word, with the operations of the sync/atomic package.
+type Word interface {
+	Load() uint32
+	Store(v uint32)
+	Add(delta uint32) uint32
+	CompareAndSwap(old, new uint32) bool
+}
+
+// Scheduler runs the goroutines of a single interleaving. Only one of its goroutines runs at a time,
+// and it runs until its next scheduling point, where the Scheduler picks the goroutine that runs next.
+type Scheduler struct {
+	parked  chan bool
+	current *thread
+	threads []*thread
+}
+
+type thread struct {
+	resume chan struct{}
+	run    func()
+}
+
+type choice struct {
+	picked  int
+	enabled int
+}
+
+// Explore runs the scenario once for every interleaving of the goroutines that it starts.
+// The scenario creates its shared state with NewWord, starts its goroutines with Go, and returns a function that
+// verifies the state once every goroutine has finished. Explore returns the number of interleavings that were run,
+// and the first verification error along with the schedule that caused it.
+func Explore(scenario func(s *Scheduler) func() error) (int, error) {
+	var prefix []int
+	for runs := 1; ; runs++ {
+		s := &Scheduler{parked: make(chan bool)}
+		verify := scenario(s)
+		choices := s.run(prefix)
+
+		if err := verify(); err != nil {
+			return runs, fmt.Errorf("schedule %v: %w", schedule(choices), err)
+		}
+
+		prefix = backtrack(choices)
+		if prefix == nil {
+			return runs, nil
+		}
+	}
+}
+
+// Go adds a goroutine to the scenario. It is started once the scenario has been set up.
+func (s *Scheduler) Go(f func()) {
+	s.threads = append(s.threads, &thread{resume: make(chan struct{}), run: f})
+}
+
+// NewWord creates a Word whose every operation is a scheduling point.
+// Outside of the goroutines of the scenario, like in the verification, its operations run without scheduling.
+func (s *Scheduler) NewWord(v uint32) Word {
+	return &word{s: s, v: v}
+}
+
+// Yield is a scheduling point for state that isn't a Word, like the slots that the queue positions point at.
+// It must be called right before every access to such state, so that the other goroutines can run in between.
+func (s *Scheduler) Yield() {
+	t := s.current
+	if t == nil {
+		return
+	}
+
+	s.parked <- false
+	<-t.resume
+}
+
+func (s *Scheduler) run(prefix []int) []choice {
+	for _, t := range s.threads {
+		go func(t *thread) {
+			<-t.resume
+			t.run()
+			s.parked <- true
+		}(t)
+	}
+
+	// Everything up to the first scheduling point is local to each goroutine, so its order doesn't matter
+	alive := make([]*thread, 0, len(s.threads))
+	for _, t := range s.threads {
+		if !s.step(t) {
+			alive = append(alive, t)
+		}
+	}
+
+	var choices []choice
+	for len(alive) > 0 {
+		picked := 0
+		if len(choices) < len(prefix) {
+			picked = prefix[len(choices)]
+		}
+		choices = append(choices, choice{picked: picked, enabled: len(alive)})
+
+		if s.step(alive[picked]) {
+			alive = append(alive[:picked], alive[picked+1:]...)
+		}
+	}
+
+	s.current = nil
+	return choices
+}
+
+// step runs the goroutine until its next scheduling point, and reports whether it has finished.
+func (s *Scheduler) step(t *thread) bool {
+	s.current = t
+	t.resume <- struct{}{}
+	return <-s.parked
+}
+
+// backtrack returns the prefix of the next schedule to explore, in depth first order,
+// or nil once every schedule has been explored.
+func backtrack(choices []choice) []int {
+	for i := len(choices) - 1; i >= 0; i-- {
+		if choices[i].picked+1 < choices[i].enabled {
+			prefix := schedule(choices[:i])
+			return append(prefix, choices[i].picked+1)
+		}
+	}
+
+	return nil
+}
+
+func schedule(choices []choice) []int {
+	picks := make([]int, len(choices), len(choices)+1)
+	for i, c := range choices {
+		picks[i] = c.picked
+	}
+
+	return picks
+}
+
+type word struct {
+	s *Scheduler
+	v uint32
+}
+
+func (w *word) Load() uint32 {
+	w.s.Yield()
+	return w.v
+}
+
+func (w *word) Store(v uint32) {
+	w.s.Yield()
+	w.v = v
+}
+
+func (w *word) Add(delta uint32) uint32 {
+	w.s.Yield()
+	w.v += delta
+	return w.v
+}
+
+func (w *word) CompareAndSwap(old, new uint32) bool {
+	w.s.Yield()
+	if w.v != old {
+		return false
+	}
+
+	w.v = new
+	return true
+}
+
+// Atomic is a Word whose operations are never scheduling points, for state that a scenario doesn't need
+// to interleave, like a flag that never changes while the goroutines run.
+type Atomic uint32
+
+func (w *Atomic) Load() uint32 {
+	return atomic.LoadUint32((*uint32)(w))
+}
+
+func (w *Atomic) Store(v uint32) {
+	atomic.StoreUint32((*uint32)(w), v)
+}
+
+func (w *Atomic) Add(delta uint32) uint32 {
+	return atomic.AddUint32((*uint32)(w), delta)
+}
+
+func (w *Atomic) CompareAndSwap(old, new uint32) bool {
+	return atomic.CompareAndSwapUint32((*uint32)(w), old, new)
+}
@@ -0,0 +1,74 @@
+package interleave
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExplore(t *testing.T) {
+	testCases := []struct {
+		increment    func(w Word)
+		desc         string
+		threads      int
+		expectedRuns int
+		lostUpdate   bool
+	}{
+		{
+			desc:         "Atomic increments are never lost",
+			increment:    func(w Word) { w.Add(1) },
+			threads:      3,
+			expectedRuns: 6,
+			lostUpdate:   false,
+		},
+		{
+			desc: "Compare and swap increments are never lost",
+			increment: func(w Word) {
+				for {
+					v := w.Load()
+					if w.CompareAndSwap(v, v+1) {
+						return
+					}
+				}
+			},
+			threads:      2,
+			expectedRuns: 6,
+			lostUpdate:   false,
+		},
+		{
+			desc: "Load and store increments are lost",
+			increment: func(w Word) {
+				w.Store(w.Load() + 1)
+			},
+			threads:      2,
+			expectedRuns: 2, // The first schedule runs the increments one after the other
+			lostUpdate:   true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			runs, err := Explore(func(s *Scheduler) func() error {
+				w := s.NewWord(0)
+				for i := 0; i < tC.threads; i++ {
+					s.Go(func() { tC.increment(w) })
+				}
+
+				return func() error {
+					if w.Load() != uint32(tC.threads) {
+						return errLostUpdate
+					}
+					return nil
+				}
+			})
+
+			if errors.Is(err, errLostUpdate) != tC.lostUpdate {
+				subT.Errorf("expected a lost update to be %t, got error %v", tC.lostUpdate, err)
+			}
+
+			if runs != tC.expectedRuns {
+				subT.Errorf("expected the runs to be %d, got %d", tC.expectedRuns, runs)
+			}
+		})
+	}
+}
+
+var errLostUpdate = errors.New("lost update")
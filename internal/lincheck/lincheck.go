@@ -0,0 +1,224 @@
+// Package lincheck records concurrent histories of queue operations, and checks them for linearizability
+// against a sequential FIFO model, in the style of Porcupine and Knossos.
+// A history is linearizable if every operation can be assigned a single point in time between its call and its
+// return, such that applying the operations in that order to a sequential FIFO queue produces the same results.
+// This catches reordered and duplicated deliveries, as well as empty and full results that could never have happened.
+package lincheck
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type Kind uint8
+
+const (
+	Push Kind = iota
+	Pop
+)
+
+// Operation is a single completed operation on the queue.
+// For a Push, Ok is false if the queue was full, and for a Pop, Ok is false if the queue was empty.
+type Operation struct {
+	Call   int64
+	Return int64
+	Value  int
+	Kind   Kind
+	Ok     bool
+}
+
+// Recorder collects the operations of a concurrent history, timestamping them with a shared logical clock.
+type Recorder struct {
+	// clock is allocated separately, since the first word of an allocation is 64 bit aligned for atomic operations
+	// on 32 bit platforms.
+	clock *int64
+	ops   []Operation
+	mu    sync.Mutex
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{clock: new(int64)}
+}
+
+// Now returns the next timestamp of the history. It should be called right before an operation
+// is called, to get its Call timestamp, and right after it returns, to get its Return timestamp.
+func (r *Recorder) Now() int64 {
+	return atomic.AddInt64(r.clock, 1)
+}
+
+// Record adds a completed operation to the history.
+func (r *Recorder) Record(op Operation) {
+	r.mu.Lock()
+	r.ops = append(r.ops, op)
+	r.mu.Unlock()
+}
+
+// History returns the operations recorded so far.
+func (r *Recorder) History() []Operation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Operation(nil), r.ops...)
+}
+
+// event is a call or return of an operation, linked in timestamp order.
+type event struct {
+	prev  *event
+	next  *event
+	match *event // The return event of a call event
+	id    int
+	call  bool
+}
+
+// Check reports whether the history is linearizable with respect to a FIFO queue that holds at most capacity values.
+func Check(history []Operation, capacity int) bool {
+	head := buildEvents(history)
+	linearized := make([]bool, len(history))
+	cache := map[string]struct{}{}
+
+	type frame struct {
+		entry *event
+		state []int
+	}
+
+	var stack []frame
+	state := []int{}
+	entry := head.next
+
+	for head.next != nil {
+		if entry.call {
+			next, ok := step(state, history[entry.id], capacity)
+			if ok {
+				linearized[entry.id] = true
+				key := cacheKey(linearized, next)
+				if _, seen := cache[key]; !seen {
+					cache[key] = struct{}{}
+					stack = append(stack, frame{entry: entry, state: state})
+					state = next
+					lift(entry)
+					entry = head.next
+					continue
+				}
+				linearized[entry.id] = false
+			}
+			entry = entry.next
+			continue
+		}
+
+		// Reaching a return means its operation had to be linearized already, so backtrack
+		if len(stack) == 0 {
+			return false
+		}
+
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		linearized[top.entry.id] = false
+		state = top.state
+		unlift(top.entry)
+		entry = top.entry.next
+	}
+
+	return true
+}
+
+// step applies the operation to the sequential FIFO model, returning the new state and whether the
+// result of the operation is possible from the given state.
+func step(state []int, op Operation, capacity int) ([]int, bool) {
+	switch {
+	case op.Kind == Push && op.Ok:
+		if len(state) >= capacity {
+			return nil, false
+		}
+		next := make([]int, len(state), len(state)+1)
+		copy(next, state)
+		return append(next, op.Value), true
+	case op.Kind == Push:
+		return state, len(state) == capacity
+	case op.Ok:
+		if len(state) == 0 || state[0] != op.Value {
+			return nil, false
+		}
+		return state[1:], true
+	default:
+		return state, len(state) == 0
+	}
+}
+
+func buildEvents(history []Operation) *event {
+	events := make([]*event, 0, len(history)*2)
+	for i := range history {
+		ret := &event{id: i}
+		call := &event{id: i, call: true, match: ret}
+		events = append(events, call, ret)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return timestamp(history, events[i]) < timestamp(history, events[j])
+	})
+
+	head := &event{}
+	prev := head
+	for _, e := range events {
+		e.prev = prev
+		prev.next = e
+		prev = e
+	}
+
+	return head
+}
+
+func timestamp(history []Operation, e *event) int64 {
+	if e.call {
+		return history[e.id].Call
+	}
+	return history[e.id].Return
+}
+
+// lift removes a call event and its matching return event from the list.
+func lift(call *event) {
+	call.prev.next = call.next
+	if call.next != nil {
+		call.next.prev = call.prev
+	}
+
+	ret := call.match
+	ret.prev.next = ret.next
+	if ret.next != nil {
+		ret.next.prev = ret.prev
+	}
+}
+
+// unlift restores a call event and its matching return event that were removed with lift.
+func unlift(call *event) {
+	ret := call.match
+	ret.prev.next = ret
+	if ret.next != nil {
+		ret.next.prev = ret
+	}
+
+	call.prev.next = call
+	if call.next != nil {
+		call.next.prev = call
+	}
+}
+
+func cacheKey(linearized []bool, state []int) string {
+	var b strings.Builder
+	for _, l := range linearized {
+		if l {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+
+	for _, v := range state {
+		b.WriteByte(',')
+		b.WriteString(strconv.Itoa(v))
+	}
+
+	return b.String()
+}
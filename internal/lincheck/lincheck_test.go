@@ -0,0 +1,81 @@
+package lincheck
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		history      []Operation
+		capacity     int
+		linearizable bool
+	}{
+		{
+			desc: "Sequential FIFO history is linearizable",
+			history: []Operation{
+				{Kind: Push, Value: 1, Ok: true, Call: 1, Return: 2},
+				{Kind: Push, Value: 2, Ok: true, Call: 3, Return: 4},
+				{Kind: Pop, Value: 1, Ok: true, Call: 5, Return: 6},
+				{Kind: Pop, Value: 2, Ok: true, Call: 7, Return: 8},
+				{Kind: Pop, Ok: false, Call: 9, Return: 10},
+			},
+			capacity:     7,
+			linearizable: true,
+		},
+		{
+			desc: "Reordered delivery is not linearizable",
+			history: []Operation{
+				{Kind: Push, Value: 1, Ok: true, Call: 1, Return: 2},
+				{Kind: Push, Value: 2, Ok: true, Call: 3, Return: 4},
+				{Kind: Pop, Value: 2, Ok: true, Call: 5, Return: 6},
+				{Kind: Pop, Value: 1, Ok: true, Call: 7, Return: 8},
+			},
+			capacity:     7,
+			linearizable: false,
+		},
+		{
+			desc: "Duplicated delivery is not linearizable",
+			history: []Operation{
+				{Kind: Push, Value: 1, Ok: true, Call: 1, Return: 2},
+				{Kind: Pop, Value: 1, Ok: true, Call: 3, Return: 4},
+				{Kind: Pop, Value: 1, Ok: true, Call: 5, Return: 6},
+			},
+			capacity:     7,
+			linearizable: false,
+		},
+		{
+			desc: "Empty result while a value is queued is not linearizable",
+			history: []Operation{
+				{Kind: Push, Value: 1, Ok: true, Call: 1, Return: 2},
+				{Kind: Pop, Ok: false, Call: 3, Return: 4},
+			},
+			capacity:     7,
+			linearizable: false,
+		},
+		{
+			desc: "Full result is linearizable only at capacity",
+			history: []Operation{
+				{Kind: Push, Value: 1, Ok: true, Call: 1, Return: 2},
+				{Kind: Push, Ok: false, Call: 3, Return: 4},
+			},
+			capacity:     1,
+			linearizable: true,
+		},
+		{
+			desc: "Overlapping operations may be linearized in either order",
+			history: []Operation{
+				{Kind: Push, Value: 1, Ok: true, Call: 1, Return: 4},
+				{Kind: Pop, Value: 1, Ok: true, Call: 2, Return: 5},
+				{Kind: Pop, Ok: false, Call: 3, Return: 6},
+			},
+			capacity:     7,
+			linearizable: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			if linearizable := Check(tC.history, tC.capacity); tC.linearizable != linearizable {
+				subT.Errorf("expected linearizable to be %t, got %t", tC.linearizable, linearizable)
+			}
+		})
+	}
+}
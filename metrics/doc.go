@@ -0,0 +1,9 @@
+// Package metrics contains an instrumented wrapper around a `micro.Q`, which counts what happens to the queue.
+// It counts committed pushes and pops, pushes rejected because the queue was full, pops that found the queue empty,
+// PopCommit calls that lost their compare and swap to another consumer, and overflow protections of the head,
+// and it tracks the high-water mark of the depth of the queue. A high rate of failed commits compared to pops
+// means that too many consumers are contending on the same queue.
+// The counters are updated atomically, so they cost a few atomic additions per operation on top of the queue itself,
+// which is why the wrapper is optional. They can be published through `expvar`, or served in the Prometheus
+// text exposition format by `metrics.Handler`, without any dependencies outside of the standard library.
+package metrics
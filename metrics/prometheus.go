@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type metric struct {
+	value func(s Stats) uint64
+	name  string
+	help  string
+	kind  string
+}
+
+var exposed = []metric{
+	{name: "q_pushes_total", kind: "counter", help: "Jobs committed to the queue.", value: func(s Stats) uint64 { return s.Pushes }},
+	{name: "q_pops_total", kind: "counter", help: "Jobs popped and committed from the queue.", value: func(s Stats) uint64 { return s.Pops }},
+	{name: "q_full_rejections_total", kind: "counter", help: "Pushes rejected because the queue was full.", value: func(s Stats) uint64 { return s.FullRejections }},
+	{name: "q_empty_polls_total", kind: "counter", help: "Pops that found the queue empty.", value: func(s Stats) uint64 { return s.EmptyPolls }},
+	{name: "q_failed_commits_total", kind: "counter", help: "Pop commits that lost to another consumer.", value: func(s Stats) uint64 { return s.FailedCommits }},
+	{name: "q_overflow_protections_total", kind: "counter", help: "Overflow protections of the head of the queue.", value: func(s Stats) uint64 { return s.OverflowProtections }},
+	{name: "q_depth", kind: "gauge", help: "Jobs committed to the queue and not yet popped.", value: func(s Stats) uint64 { return uint64(s.Depth) }},
+	{name: "q_depth_high_water_mark", kind: "gauge", help: "Highest depth that the queue has reached.", value: func(s Stats) uint64 { return uint64(s.HighWaterMark) }},
+}
+
+// WritePrometheus writes the counters of the queues in the Prometheus text exposition format,
+// with the name of every queue in the `queue` label.
+func WritePrometheus(w io.Writer, queues map[string]*Q) error {
+	names := make([]string, 0, len(queues))
+	for name := range queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stats := make([]Stats, len(names))
+	for i, name := range names {
+		stats[i] = queues[name].Stats()
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, m := range exposed {
+		bw.WriteString("# HELP " + m.name + " " + m.help + "\n")
+		bw.WriteString("# TYPE " + m.name + " " + m.kind + "\n")
+		for i, name := range names {
+			bw.WriteString(m.name + `{queue="` + labelEscaper.Replace(name) + `"} `)
+			bw.WriteString(strconv.FormatUint(m.value(stats[i]), 10) + "\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Handler returns an http.Handler that serves the counters of the queues in the Prometheus text exposition format.
+// The map must not be modified after it is passed to Handler.
+func Handler(queues map[string]*Q) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		WritePrometheus(w, queues)
+	})
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	jobs := NewQ(3)
+	for i := 0; i < 3; i++ {
+		jobs.Push()
+		jobs.PushCommit()
+	}
+	_, savepoint, _ := jobs.Pop()
+	jobs.PopCommit(savepoint)
+	jobs.PopCommit(savepoint)
+
+	events := NewQ(3)
+	events.Pop()
+
+	rec := httptest.NewRecorder()
+	Handler(map[string]*Q{"jobs": jobs, `"events"`: events}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if contentType := rec.Header().Get("Content-Type"); contentType != ContentType {
+		t.Errorf("expected the content type to be %q, got %q", ContentType, contentType)
+	}
+
+	body, _ := io.ReadAll(rec.Body)
+	testCases := []struct {
+		desc string
+		line string
+	}{
+		{desc: "Metrics have a help line", line: "# HELP q_pushes_total Jobs committed to the queue."},
+		{desc: "Metrics have a type line", line: "# TYPE q_failed_commits_total counter"},
+		{desc: "Counters are labeled with the queue", line: `q_pushes_total{queue="jobs"} 3`},
+		{desc: "Failed commits are exposed", line: `q_failed_commits_total{queue="jobs"} 1`},
+		{desc: "Gauges are exposed", line: `q_depth{queue="jobs"} 2`},
+		{desc: "High-water marks are exposed", line: `q_depth_high_water_mark{queue="jobs"} 3`},
+		{desc: "Label values are escaped", line: `q_empty_polls_total{queue="\"events\""} 1`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			if !strings.Contains(string(body), tC.line+"\n") {
+				subT.Errorf("expected the exposition to contain %q, got:\n%s", tC.line, body)
+			}
+		})
+	}
+}
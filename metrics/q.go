@@ -0,0 +1,266 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync/atomic"
+
+	"github.com/probably-not/q/micro"
+)
+
+// Stats is a snapshot of the counters of a Q.
+type Stats struct {
+	Pushes              uint64 `json:"pushes"`
+	Pops                uint64 `json:"pops"`
+	FullRejections      uint64 `json:"full_rejections"`
+	EmptyPolls          uint64 `json:"empty_polls"`
+	FailedCommits       uint64 `json:"failed_commits"`
+	OverflowProtections uint64 `json:"overflow_protections"`
+	Depth               int    `json:"depth"`
+	HighWaterMark       int    `json:"high_water_mark"`
+}
+
+// Q is a `micro.Q` that counts its operations. It has the same operations, with the same
+// semantics and caveats, as the `micro.Q` that it wraps. PushWait and PopWait retry the counted Push and Pop
+// of the wrapper, so every attempt that finds the queue full or empty is counted, while PushCtx and PopCtx
+// wrap those of `micro.Q`, and only count the attempt that gives up once the context is done.
+type Q struct {
+	q *micro.Q
+	// counters are allocated separately, since the first word of an allocation is 64 bit aligned
+	// for atomic operations on 32 bit platforms.
+	c *counters
+}
+
+type counters struct {
+	pushes         uint64
+	pops           uint64
+	fullRejections uint64
+	emptyPolls     uint64
+	failedCommits  uint64
+	highWaterMark  uint64
+}
+
+func NewQ(queueSizeFactor int) *Q {
+	return &Q{
+		q: micro.NewQ(queueSizeFactor),
+		c: &counters{},
+	}
+}
+
+// NewQChecked creates a new queue after validating the size factor.
+// If the factor can't be used with the queue, `micro.ErrFactorZero` or `micro.ErrFactorTooLarge` is returned.
+func NewQChecked(queueSizeFactor int) (*Q, error) {
+	q, err := micro.NewQChecked(queueSizeFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Q{q: q, c: &counters{}}, nil
+}
+
+// Pop is `micro.Q.Pop`, counting the pops that find the queue empty.
+func (q *Q) Pop() (int, uint32, bool) {
+	pos, savepoint, isEmpty := q.q.Pop()
+	if isEmpty && pos != micro.Closed {
+		atomic.AddUint64(&q.c.emptyPolls, 1)
+	}
+
+	return pos, savepoint, isEmpty
+}
+
+// PopCommit is `micro.Q.PopCommit`, counting the commits that succeed and the commits that fail.
+func (q *Q) PopCommit(savepoint uint32) bool {
+	if !q.q.PopCommit(savepoint) {
+		atomic.AddUint64(&q.c.failedCommits, 1)
+		return false
+	}
+
+	atomic.AddUint64(&q.c.pops, 1)
+	return true
+}
+
+// Push is `micro.Q.Push`, counting the pushes that find the queue full.
+func (q *Q) Push() (int, bool) {
+	pos, isFull := q.q.Push()
+	if isFull && pos != micro.Closed {
+		atomic.AddUint64(&q.c.fullRejections, 1)
+	}
+
+	return pos, isFull
+}
+
+// PushCommit is `micro.Q.PushCommit`, counting the pushes and updating the high-water mark of the depth.
+func (q *Q) PushCommit() {
+	q.q.PushCommit()
+	atomic.AddUint64(&q.c.pushes, 1)
+	q.markDepth()
+}
+
+// PushN is `micro.Q.PushN`, counting the batches that find the queue full.
+func (q *Q) PushN(n int) (int, int) {
+	start, count := q.q.PushN(n)
+	if count == 0 && start != micro.Closed {
+		atomic.AddUint64(&q.c.fullRejections, 1)
+	}
+
+	return start, count
+}
+
+// PushCommitN is `micro.Q.PushCommitN`, counting every push of the batch and updating the high-water mark of the depth.
+func (q *Q) PushCommitN(count int) {
+	q.q.PushCommitN(count)
+	atomic.AddUint64(&q.c.pushes, uint64(count))
+	q.markDepth()
+}
+
+// PopN is `micro.Q.PopN`, counting the batches that find the queue empty.
+func (q *Q) PopN(n int) (int, int, uint32) {
+	start, count, savepoint := q.q.PopN(n)
+	if count == 0 && start != micro.Closed {
+		atomic.AddUint64(&q.c.emptyPolls, 1)
+	}
+
+	return start, count, savepoint
+}
+
+// PopCommitN is `micro.Q.PopCommitN`, counting every pop of a batch that commits, and the commits that fail.
+func (q *Q) PopCommitN(savepoint uint32, count int) bool {
+	if !q.q.PopCommitN(savepoint, count) {
+		atomic.AddUint64(&q.c.failedCommits, 1)
+		return false
+	}
+
+	atomic.AddUint64(&q.c.pops, uint64(count))
+	return true
+}
+
+// Peek is `micro.Q.Peek`, counting the peeks that find the queue empty.
+func (q *Q) Peek() (int, uint32, bool) {
+	pos, token, isEmpty := q.q.Peek()
+	if isEmpty && pos != micro.Closed {
+		atomic.AddUint64(&q.c.emptyPolls, 1)
+	}
+
+	return pos, token, isEmpty
+}
+
+// PopCommitPeek is `micro.Q.PopCommitPeek`, counting the commits that succeed and the commits that fail.
+func (q *Q) PopCommitPeek(token uint32) bool {
+	if !q.q.PopCommitPeek(token) {
+		atomic.AddUint64(&q.c.failedCommits, 1)
+		return false
+	}
+
+	atomic.AddUint64(&q.c.pops, 1)
+	return true
+}
+
+// PushWait is `micro.Q.PushWait`, counting every attempt that finds the queue full.
+func (q *Q) PushWait(ws micro.WaitStrategy) int {
+	for attempt := 0; ; attempt++ {
+		pos, isFull := q.Push()
+		if !isFull || pos == micro.Closed {
+			return pos
+		}
+
+		ws.Wait(attempt)
+	}
+}
+
+// PopWait is `micro.Q.PopWait`, counting every attempt that finds the queue empty.
+func (q *Q) PopWait(ws micro.WaitStrategy) (int, uint32) {
+	for attempt := 0; ; attempt++ {
+		pos, savepoint, isEmpty := q.Pop()
+		if !isEmpty || pos == micro.Closed {
+			return pos, savepoint
+		}
+
+		ws.Wait(attempt)
+	}
+}
+
+// PushCtx is `micro.Q.PushCtx`, counting a full rejection if the context is done while the queue is full.
+func (q *Q) PushCtx(ctx context.Context) (int, error) {
+	pos, err := q.q.PushCtx(ctx)
+	if err != nil && !errors.Is(err, micro.ErrClosed) {
+		atomic.AddUint64(&q.c.fullRejections, 1)
+	}
+
+	return pos, err
+}
+
+// PopCtx is `micro.Q.PopCtx`, counting an empty poll if the context is done while the queue is empty.
+func (q *Q) PopCtx(ctx context.Context) (int, uint32, error) {
+	pos, savepoint, err := q.q.PopCtx(ctx)
+	if err != nil && !errors.Is(err, micro.ErrClosed) {
+		atomic.AddUint64(&q.c.emptyPolls, 1)
+	}
+
+	return pos, savepoint, err
+}
+
+// Len returns the number of jobs that are committed to the queue and not yet popped.
+func (q *Q) Len() int {
+	return q.q.Len()
+}
+
+// Cap returns the number of jobs that the queue can hold.
+func (q *Q) Cap() int {
+	return q.q.Cap()
+}
+
+// IsEmpty reports whether the queue is empty, without counting an empty poll.
+func (q *Q) IsEmpty() bool {
+	return q.q.IsEmpty()
+}
+
+// IsFull reports whether the queue is full, without counting a full rejection.
+func (q *Q) IsFull() bool {
+	return q.q.IsFull()
+}
+
+// Close closes the queue, as `micro.Q.Close` does.
+func (q *Q) Close() {
+	q.q.Close()
+}
+
+// IsClosed reports whether Close has been called on the queue.
+func (q *Q) IsClosed() bool {
+	return q.q.IsClosed()
+}
+
+// Stats returns a snapshot of the counters of the queue.
+// Every counter is loaded atomically, but the snapshot as a whole is not taken atomically.
+func (q *Q) Stats() Stats {
+	return Stats{
+		Pushes:              atomic.LoadUint64(&q.c.pushes),
+		Pops:                atomic.LoadUint64(&q.c.pops),
+		FullRejections:      atomic.LoadUint64(&q.c.fullRejections),
+		EmptyPolls:          atomic.LoadUint64(&q.c.emptyPolls),
+		FailedCommits:       atomic.LoadUint64(&q.c.failedCommits),
+		OverflowProtections: uint64(q.q.OverflowProtections()),
+		Depth:               q.q.Len(),
+		HighWaterMark:       int(atomic.LoadUint64(&q.c.highWaterMark)),
+	}
+}
+
+// markDepth raises the high-water mark to the current depth of the queue, if the depth is higher.
+func (q *Q) markDepth() {
+	depth := uint64(q.q.Len())
+	for {
+		mark := atomic.LoadUint64(&q.c.highWaterMark)
+		if depth <= mark || atomic.CompareAndSwapUint64(&q.c.highWaterMark, mark, depth) {
+			return
+		}
+	}
+}
+
+// Publish publishes the counters of the queue as an `expvar` variable with the name,
+// which is served as JSON by the `/debug/vars` handler of the expvar package.
+// Like `expvar.Publish`, it panics if the name is already in use.
+func (q *Q) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return q.Stats()
+	}))
+}
@@ -0,0 +1,276 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	testCases := []struct {
+		run      func(q *Q)
+		desc     string
+		expected Stats
+	}{
+		{
+			desc: "Committed pushes and pops are counted",
+			run: func(q *Q) {
+				for i := 0; i < 5; i++ {
+					q.Push()
+					q.PushCommit()
+				}
+				for i := 0; i < 3; i++ {
+					_, savepoint, _ := q.Pop()
+					q.PopCommit(savepoint)
+				}
+			},
+			expected: Stats{Pushes: 5, Pops: 3, Depth: 2, HighWaterMark: 5},
+		},
+		{
+			desc: "Pushes to a full queue are counted as rejections",
+			run: func(q *Q) {
+				for i := 0; i < 10; i++ {
+					if _, isFull := q.Push(); !isFull {
+						q.PushCommit()
+					}
+				}
+			},
+			expected: Stats{Pushes: 7, FullRejections: 3, Depth: 7, HighWaterMark: 7},
+		},
+		{
+			desc: "Pops from an empty queue are counted as empty polls",
+			run: func(q *Q) {
+				q.Pop()
+				q.Pop()
+			},
+			expected: Stats{EmptyPolls: 2},
+		},
+		{
+			desc: "Closed results are not counted as rejections or empty polls",
+			run: func(q *Q) {
+				q.Close()
+				q.Push()
+				q.Pop()
+			},
+			expected: Stats{},
+		},
+		{
+			desc: "Commits with a stale savepoint are counted as failed",
+			run: func(q *Q) {
+				q.Push()
+				q.PushCommit()
+				_, savepoint, _ := q.Pop()
+				q.PopCommit(savepoint)
+				q.PopCommit(savepoint)
+			},
+			expected: Stats{Pushes: 1, Pops: 1, FailedCommits: 1, HighWaterMark: 1},
+		},
+		{
+			desc: "Overflow protections of the head are counted",
+			run: func(q *Q) {
+				for i := 0; i < 0x10001; i++ {
+					q.Push()
+					q.PushCommit()
+					_, savepoint, _ := q.Pop()
+					q.PopCommit(savepoint)
+				}
+			},
+			expected: Stats{Pushes: 0x10001, Pops: 0x10001, OverflowProtections: 2, HighWaterMark: 1},
+		},
+		{
+			desc: "Batches are counted per job",
+			run: func(q *Q) {
+				_, count := q.PushN(5)
+				q.PushCommitN(count)
+				_, count, savepoint := q.PopN(3)
+				q.PopCommitN(savepoint, count)
+				q.PopCommitN(savepoint, count)
+				q.PushN(6)
+			},
+			expected: Stats{Pushes: 5, Pops: 3, FailedCommits: 1, Depth: 2, HighWaterMark: 5},
+		},
+		{
+			desc: "Batches to a full queue and from an empty queue are counted",
+			run: func(q *Q) {
+				q.PopN(1)
+				_, count := q.PushN(7)
+				q.PushCommitN(count)
+				q.PushN(1)
+			},
+			expected: Stats{Pushes: 7, FullRejections: 1, EmptyPolls: 1, Depth: 7, HighWaterMark: 7},
+		},
+		{
+			desc: "Overflow protections of batches are counted",
+			run: func(q *Q) {
+				for i := 0; i < 0x4001; i++ {
+					_, count := q.PushN(4)
+					q.PushCommitN(count)
+					_, count, savepoint := q.PopN(4)
+					q.PopCommitN(savepoint, count)
+				}
+			},
+			expected: Stats{Pushes: 0x10004, Pops: 0x10004, OverflowProtections: 2, HighWaterMark: 4},
+		},
+		{
+			desc: "Peeks are counted as pops",
+			run: func(q *Q) {
+				q.Peek()
+				q.Push()
+				q.PushCommit()
+				_, token, _ := q.Peek()
+				q.PopCommitPeek(token)
+				q.PopCommitPeek(token)
+			},
+			expected: Stats{Pushes: 1, Pops: 1, EmptyPolls: 1, FailedCommits: 1, HighWaterMark: 1},
+		},
+		{
+			desc: "Every attempt of a blocking push is counted",
+			run: func(q *Q) {
+				for i := 0; i < 7; i++ {
+					q.Push()
+					q.PushCommit()
+				}
+				q.PushWait(popOnWait{q: q})
+				q.PushCommit()
+			},
+			expected: Stats{Pushes: 8, Pops: 1, FullRejections: 1, Depth: 7, HighWaterMark: 7},
+		},
+		{
+			desc: "Context aware operations are counted",
+			run: func(q *Q) {
+				q.PushCtx(context.Background())
+				q.PushCommit()
+				_, savepoint, _ := q.PopCtx(context.Background())
+				q.PopCommit(savepoint)
+			},
+			expected: Stats{Pushes: 1, Pops: 1, HighWaterMark: 1},
+		},
+		{
+			desc: "Context aware operations that give up are counted once",
+			run: func(q *Q) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				q.PopCtx(ctx)
+				for i := 0; i < 7; i++ {
+					q.Push()
+					q.PushCommit()
+				}
+				q.PushCtx(ctx)
+			},
+			expected: Stats{Pushes: 7, FullRejections: 1, EmptyPolls: 1, Depth: 7, HighWaterMark: 7},
+		},
+		{
+			desc: "Context aware operations on a closed queue are not counted",
+			run: func(q *Q) {
+				q.Close()
+				q.PushCtx(context.Background())
+				q.PopCtx(context.Background())
+			},
+			expected: Stats{},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q := NewQ(3)
+			tC.run(q)
+
+			if stats := q.Stats(); stats != tC.expected {
+				subT.Errorf("expected the stats to be %+v, got %+v", tC.expected, stats)
+			}
+		})
+	}
+}
+
+// popOnWait is a WaitStrategy which frees a slot of the queue by popping from it on every wait.
+type popOnWait struct {
+	q *Q
+}
+
+func (w popOnWait) Wait(int) {
+	_, savepoint, _ := w.q.Pop()
+	w.q.PopCommit(savepoint)
+}
+
+func (popOnWait) Notify() {}
+
+func TestStatsConcurrent(t *testing.T) {
+	q := NewQ(4)
+	completedProducing := int32(0)
+
+	var wg sync.WaitGroup
+	wg.Add(1) // Add producer goroutine
+
+	// Producer
+	go func() {
+		defer wg.Done()
+		defer atomic.StoreInt32(&completedProducing, 1)
+
+		for i := 0; i < 1000; i++ {
+			if _, isFull := q.Push(); isFull {
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				i--
+				continue
+			}
+			q.PushCommit()
+		}
+	}()
+
+	// Consumers
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				_, savepoint, isEmpty := q.Pop()
+				if isEmpty {
+					if atomic.LoadInt32(&completedProducing) == 1 && q.IsEmpty() {
+						break
+					}
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					continue
+				}
+
+				q.PopCommit(savepoint)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	stats := q.Stats()
+	if stats.Pushes != 1000 || stats.Pops != 1000 {
+		t.Errorf("expected 1000 pushes and pops, got %d pushes and %d pops", stats.Pushes, stats.Pops)
+	}
+
+	if stats.HighWaterMark > q.Cap() {
+		t.Errorf("expected the high-water mark to be at most %d, got %d", q.Cap(), stats.HighWaterMark)
+	}
+}
+
+// publishRuns makes the published name unique per run, since expvar panics when a name is published twice.
+var publishRuns int32
+
+func TestPublish(t *testing.T) {
+	name := fmt.Sprintf("metrics_test_queue_%d", atomic.AddInt32(&publishRuns, 1))
+	q := NewQ(3)
+	q.Push()
+	q.PushCommit()
+	q.Publish(name)
+
+	var stats Stats
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &stats); err != nil {
+		t.Fatalf("unexpected error decoding the published stats %v", err)
+	}
+
+	if stats != q.Stats() {
+		t.Errorf("expected the published stats to be %+v, got %+v", q.Stats(), stats)
+	}
+}
@@ -9,10 +9,17 @@ import (
 
 // BenchmarkProduce-10    	  141670	      8963 ns/op	       0 B/op	       0 allocs/op
 func BenchmarkProduce(b *testing.B) {
+	benchmarkProduce(b, NewQ(6))
+}
+
+func BenchmarkPaddedProduce(b *testing.B) {
+	benchmarkProduce(b, &NewPaddedQ(6).Q)
+}
+
+func benchmarkProduce(b *testing.B, q *Q) {
 	b.StopTimer()
 
 	const queueSizeFactor = 6
-	q := NewQ(queueSizeFactor)
 	const availableSlots = 1 << queueSizeFactor
 	jobs := [availableSlots]int{}
 	var wg sync.WaitGroup
@@ -27,7 +34,7 @@ func BenchmarkProduce(b *testing.B) {
 		defer wg.Done()
 
 		for {
-			slot, savepoint, isEmpty := q.Pop(queueSizeFactor)
+			slot, savepoint, isEmpty := q.Pop()
 			if isEmpty {
 				if atomic.LoadInt32(&completedProducing) > 0 {
 					break
@@ -51,7 +58,7 @@ func BenchmarkProduce(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		// Producer is inside the loop so we can measure producing performance
 		for i := 0; i < 1000; i++ {
-			slot, isFull := q.Push(queueSizeFactor)
+			slot, isFull := q.Push()
 			if isFull {
 				continue
 			}
@@ -75,10 +82,17 @@ func BenchmarkProduce(b *testing.B) {
 
 // BenchmarkConsume-10    	877648965	         1.362 ns/op	       0 B/op	       0 allocs/op
 func BenchmarkConsume(b *testing.B) {
+	benchmarkConsume(b, NewQ(6))
+}
+
+func BenchmarkPaddedConsume(b *testing.B) {
+	benchmarkConsume(b, &NewPaddedQ(6).Q)
+}
+
+func benchmarkConsume(b *testing.B, q *Q) {
 	b.StopTimer()
 
 	const queueSizeFactor = 6
-	q := NewQ(queueSizeFactor)
 	const availableSlots = 1 << queueSizeFactor
 	jobs := [availableSlots]int{}
 	var wg sync.WaitGroup
@@ -97,7 +111,7 @@ func BenchmarkConsume(b *testing.B) {
 		fullAttempts := 0
 
 		for i := 0; i < 1000; i++ {
-			slot, isFull := q.Push(queueSizeFactor)
+			slot, isFull := q.Push()
 			if isFull {
 				fullAttempts++
 				if fullAttempts > 1000 {
@@ -120,7 +134,7 @@ func BenchmarkConsume(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		// Consumer is inside the loop so we can measure consuming performance
 		for {
-			slot, savepoint, isEmpty := q.Pop(queueSizeFactor)
+			slot, savepoint, isEmpty := q.Pop()
 			if isEmpty {
 				if atomic.LoadInt32(&completedProducing) > 0 {
 					break
@@ -143,3 +157,57 @@ func BenchmarkConsume(b *testing.B) {
 		b.Errorf("expected the sum to be %d but got %d", producedSum, sum)
 	}
 }
+
+// BenchmarkQueues runs a producer and a consumer on each of the queues of a slice, where neighbouring queues share cache lines.
+func BenchmarkQueues(b *testing.B) {
+	queues := make([]*Q, 32)
+	for i := range queues {
+		queues[i] = NewQ(6)
+	}
+
+	benchmarkQueues(b, queues)
+}
+
+// BenchmarkPaddedQueues runs a producer and a consumer on each of the queues of a slice, where every queue has its own cache line.
+func BenchmarkPaddedQueues(b *testing.B) {
+	padded := make([]PaddedQ, 32)
+	InitPadded(padded, 6)
+
+	queues := make([]*Q, len(padded))
+	for i := range padded {
+		queues[i] = &padded[i].Q
+	}
+
+	benchmarkQueues(b, queues)
+}
+
+func benchmarkQueues(b *testing.B, queues []*Q) {
+	// The iterations are split over the queues, as RunParallel splits them over its goroutines
+	perQueue := (b.N + len(queues) - 1) / len(queues)
+
+	var wg sync.WaitGroup
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	// Exactly one goroutine runs on every queue, which keeps the single producer guarantee of the queue,
+	// so the only contention between the goroutines is on shared cache lines
+	for _, q := range queues {
+		wg.Add(1)
+
+		go func(q *Q) {
+			defer wg.Done()
+
+			for i := 0; i < perQueue; i++ {
+				if _, isFull := q.Push(); !isFull {
+					q.PushCommit()
+				}
+
+				if _, savepoint, isEmpty := q.Pop(); !isEmpty {
+					q.PopCommit(savepoint)
+				}
+			}
+		}(q)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,157 @@
+package micro
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		pushes         int
+		expectedDrains int
+	}{
+		{
+			desc:           "Closing an empty queue reports closed on the first pop",
+			pushes:         0,
+			expectedDrains: 0,
+		},
+		{
+			desc:           "Closing a queue with jobs drains them before reporting closed",
+			pushes:         10,
+			expectedDrains: 10,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q := NewQ(6)
+			for i := 0; i < tC.pushes; i++ {
+				q.PushCommit()
+			}
+
+			q.Close()
+			q.Close() // Closing twice is a no-op
+
+			if !q.IsClosed() {
+				subT.Errorf("expected the queue to be closed")
+			}
+
+			idx, isFull := q.Push()
+			if idx != Closed || !isFull {
+				subT.Errorf("expected push on a closed queue to return %d and true, got %d and %t", Closed, idx, isFull)
+			}
+
+			for i := 0; i < tC.expectedDrains; i++ {
+				idx, savepoint, isEmpty := q.Pop()
+				if isEmpty {
+					subT.Errorf("unexpected empty queue during drain at pop number %d", i)
+					return
+				}
+
+				if i != idx {
+					subT.Errorf("expected popped job to be %d but got %d", i, idx)
+				}
+
+				if !q.PopCommit(savepoint) {
+					subT.Errorf("expected pop commit to pass normally on job %d but got commit failed", i)
+				}
+			}
+
+			idx, _, isEmpty := q.Pop()
+			if idx != Closed || !isEmpty {
+				subT.Errorf("expected pop on a drained closed queue to return %d and true, got %d and %t", Closed, idx, isEmpty)
+			}
+
+			if _, err := q.PushCtx(context.Background()); !errors.Is(err, ErrClosed) {
+				subT.Errorf("expected the push error to be %v, got %v", ErrClosed, err)
+			}
+
+			if _, _, err := q.PopCtx(context.Background()); !errors.Is(err, ErrClosed) {
+				subT.Errorf("expected the pop error to be %v, got %v", ErrClosed, err)
+			}
+
+			if idx := q.PushWait(Spin{}); idx != Closed {
+				subT.Errorf("expected push wait on a closed queue to return %d, got %d", Closed, idx)
+			}
+
+			if idx, _ := q.PopWait(Spin{}); idx != Closed {
+				subT.Errorf("expected pop wait on a drained closed queue to return %d, got %d", Closed, idx)
+			}
+		})
+	}
+}
+
+func TestCloseConcurrentDrain(t *testing.T) {
+	q := NewQ(6)
+	const availableSlots = 1 << 6
+	// [availableSlots]int64{} is the underlying type of the atomic value.
+	atomicJobs := &atomic.Value{}
+	atomicJobs.Store([availableSlots]int64{})
+
+	var wg sync.WaitGroup
+	wg.Add(1) // Add producer goroutine
+
+	// Producer
+	producedSum := int64(0)
+	go func() {
+		defer func() {
+			q.Close()
+			wg.Done()
+		}()
+
+		for i := int64(0); i < 1000; i++ {
+			slot, isFull := q.Push()
+			if isFull {
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				i--
+				continue
+			}
+
+			jobs := atomicJobs.Load().([availableSlots]int64)
+			newJobs := jobs
+			newJobs[slot] = i
+			atomicJobs.Store(newJobs)
+			producedSum += i
+			q.PushCommit()
+		}
+	}()
+
+	// Consumers stop on the closed result instead of a separate completion flag
+	sum := int64(0)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				slot, savepoint, isEmpty := q.Pop()
+				if isEmpty {
+					if slot == Closed {
+						break
+					}
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					continue
+				}
+
+				jobs := atomicJobs.Load().([availableSlots]int64)
+				job := jobs[slot]
+				if !q.PopCommit(savepoint) {
+					continue // Commit failed so we can't run the job
+				}
+
+				atomic.AddInt64(&sum, job)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if producedSum != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+}
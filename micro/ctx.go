@@ -0,0 +1,83 @@
+package micro
+
+import (
+	"context"
+	"time"
+)
+
+// ctxBackoff is how long the context aware operations sleep between attempts while the queue is full or empty.
+var ctxBackoff = Backoff{Min: 1 * time.Microsecond, Max: 1 * time.Millisecond}
+
+// PushCtx will block until a position can be pushed to in the queue, or until the context is done.
+// It returns the position, after which PushCommit must be called as it would be after Push.
+// If the context is done before a position is available, `-1, ctx.Err()` will be returned.
+// If the queue is closed, `-1, ErrClosed` will be returned.
+func (q *Q) PushCtx(ctx context.Context) (int, error) {
+	for attempt := 0; ; attempt++ {
+		pos, isFull := q.Push()
+		if !isFull {
+			return pos, nil
+		}
+
+		if pos == Closed {
+			return -1, ErrClosed
+		}
+
+		if err := ctxBackoff.WaitCtx(ctx, attempt); err != nil {
+			return -1, err
+		}
+	}
+}
+
+// PopCtx will block until a position can be popped from the queue, or until the context is done.
+// It returns the position and a savepoint, after which PopCommit must be called as it would be after Pop.
+// If the context is done before a position is available, `-1, 0, ctx.Err()` will be returned.
+// If the queue is closed and drained, `-1, 0, ErrClosed` will be returned.
+func (q *Q) PopCtx(ctx context.Context) (int, uint32, error) {
+	for attempt := 0; ; attempt++ {
+		pos, savepoint, isEmpty := q.Pop()
+		if !isEmpty {
+			return pos, savepoint, nil
+		}
+
+		if pos == Closed {
+			return -1, 0, ErrClosed
+		}
+
+		if err := ctxBackoff.WaitCtx(ctx, attempt); err != nil {
+			return -1, 0, err
+		}
+	}
+}
+
+// PushCtx will block until the value can be pushed to the ring, or until the context is done.
+// If the context is done before the value is pushed, the context's error is returned,
+// and if the ring is closed, ErrClosed is returned.
+func (r *Ring[T]) PushCtx(ctx context.Context, v T) error {
+	pos, err := r.Q.PushCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.store(pos, v)
+	r.PushCommit()
+	return nil
+}
+
+// PopCtx will block until a value can be popped from the ring, or until the context is done.
+// If the context is done before a value is popped, the zero value of `T` and the context's error are returned,
+// and if the ring is closed and drained, the zero value of `T` and ErrClosed are returned.
+func (r *Ring[T]) PopCtx(ctx context.Context) (T, error) {
+	for {
+		pos, savepoint, err := r.Q.PopCtx(ctx)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+
+		v := r.load(pos)
+		if r.PopCommit(savepoint) {
+			return v, nil
+		}
+	}
+}
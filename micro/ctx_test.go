@@ -0,0 +1,132 @@
+package micro
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPushCtxPopCtx(t *testing.T) {
+	testCases := []struct {
+		expectedPushErr error
+		expectedPopErr  error
+		desc            string
+		pushes          int
+	}{
+		{
+			desc:            "Operations on a queue with room and jobs do not wait",
+			pushes:          1,
+			expectedPushErr: nil,
+			expectedPopErr:  nil,
+		},
+		{
+			desc:            "Pop on an empty queue returns the deadline error",
+			pushes:          0,
+			expectedPushErr: nil,
+			expectedPopErr:  context.DeadlineExceeded,
+		},
+		{
+			desc:            "Push on a full queue returns the deadline error",
+			pushes:          (1 << 6) - 1,
+			expectedPushErr: context.DeadlineExceeded,
+			expectedPopErr:  nil,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q := NewQ(6)
+			for i := 0; i < tC.pushes; i++ {
+				q.PushCommit()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			pos, err := q.PushCtx(ctx)
+			if !errors.Is(err, tC.expectedPushErr) {
+				subT.Errorf("expected the push error to be %v, got %v", tC.expectedPushErr, err)
+			}
+
+			if err != nil && pos != -1 {
+				subT.Errorf("expected the push position to be -1 on error, got %d", pos)
+			}
+
+			pos, savepoint, err := q.PopCtx(ctx)
+			if !errors.Is(err, tC.expectedPopErr) {
+				subT.Errorf("expected the pop error to be %v, got %v", tC.expectedPopErr, err)
+			}
+
+			if err != nil && (pos != -1 || savepoint != 0) {
+				subT.Errorf("expected the pop position and savepoint to be -1 and 0 on error, got %d and %d", pos, savepoint)
+			}
+		})
+	}
+}
+
+func TestPopCtxCancel(t *testing.T) {
+	q := NewQ(6)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		<-time.After(5 * time.Millisecond)
+		cancel()
+	}()
+
+	if _, _, err := q.PopCtx(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the pop error to be %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestRingPushCtxPopCtx(t *testing.T) {
+	const queueSizeFactor = 4 // Small queue so that both sides have to wait
+	r := NewRing[int](queueSizeFactor)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2) // Add producer and consumer goroutines
+
+	// Producer
+	producedSum := 0
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 1000; i++ {
+			if err := r.PushCtx(ctx, i); err != nil {
+				t.Errorf("unexpected push error %v", err)
+				return
+			}
+			producedSum += i
+		}
+	}()
+
+	// Consumer
+	sum := 0
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 1000; i++ {
+			job, err := r.PopCtx(ctx)
+			if err != nil {
+				t.Errorf("unexpected pop error %v", err)
+				return
+			}
+			sum += job
+		}
+	}()
+
+	wg.Wait()
+
+	if producedSum != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+
+	expiredCtx, expiredCancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
+	defer expiredCancel()
+
+	if job, err := r.PopCtx(expiredCtx); !errors.Is(err, context.DeadlineExceeded) || job != 0 {
+		t.Errorf("expected the zero value and %v on an empty ring, got %d and %v", context.DeadlineExceeded, job, err)
+	}
+}
@@ -3,12 +3,37 @@
 // with the indices of jobs pushed to the queue and jobs popped from the queue fully managed by the `micro.Q`.
 // The slice of jobs itself is managed by an outside source, however access to this slice of jobs should be fully
 // managed by the `micro.Q` type.
+// Package micro is not safe to use in a multiple producer/multiple consumer scenario, however with a single producer
+// you may have multiple consumers.
+//
 // In the more minimalistic implementations, the size factor of the queue is something that needs to be held outside of
 // the queue, and the queue simply receives the size factor from the caller. While this allows for an extremely minimalistic
 // implementation, it means that the size factor must be passed to all of the callers of the queue, in order to ensure
 // that everyone has the correct factor. This can lead to issues with accidental losses of the factor.
 // In this implementation, we sacrifice minimalism and size with usability, and we convert the queue from a raw `uint32`
-// to a struct, which will hold the queue itself, along with the size factor.
-// Package micro is not safe to use in a multiple producer/multiple consumer scenario, however with a single producer
-// you may have multiple consumers.
+// to a struct, which will hold the queue itself, along with the size factor. Push and Pop read the size factor from
+// the queue, so callers never pass it around.
+//
+// On top of Push and Pop, `micro.Q` has:
+//   - Close, which marks the queue as closed for graceful shutdown: Push stops accepting jobs, and Pop keeps
+//     draining the jobs already in the queue before returning the `micro.Closed` position.
+//   - Peek, which returns the next position without claiming it, so a consumer can inspect the job before deciding
+//     to claim it with PopCommitPeek.
+//   - PushN and PopN, which reserve contiguous ranges of positions, so that a batch of jobs costs a single commit.
+//   - PushWait and PopWait, which block until a position is available, using a pluggable `micro.WaitStrategy` to
+//     decide how to wait between attempts, instead of every caller hand rolling a polling loop.
+//   - PushCtx and PopCtx, which block in the same way, but give up with the context's error once the context is
+//     cancelled or its deadline passes.
+//
+// The other types in the package build on `micro.Q`:
+//   - `micro.Ring` wraps the queue together with a typed slice of jobs, and handles the Pop/PopCommit retry internally.
+//     Its slots are atomic, so every push of a value that isn't pointer shaped allocates.
+//   - `micro.Selector` pops from whichever of several queues has a job, in a weighted round-robin order.
+//   - `micro.PaddedQ` keeps the state word of each queue on a cache line of its own when many queues are placed
+//     side by side, so that commits to one queue don't slow down the cores working on its neighbours.
+//   - `micro.Sharded` holds one ring per shard, so that several producers can push without a compare and swap,
+//     each to a shard of its own, while consumers steal across the shards.
+//   - `micro.Q64` is a `uint64` backed variant for queues that need more than 2^15 slots, with 32 bit halves for the
+//     head and tail. It only has Push, PushCommit, Pop, PopCommit, Len, Cap, IsEmpty and IsFull: closing, peeking,
+//     batches and blocking are only available on `micro.Q`.
 package micro
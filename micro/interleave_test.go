@@ -0,0 +1,283 @@
+package micro
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/probably-not/q/internal/consts"
+	"github.com/probably-not/q/internal/interleave"
+)
+
+// scheduledQ runs the operations of Q on state words, step by step as Q runs them on its atomic state,
+// and with the same decoding of the acquired state. TestScheduledQMatchesQ keeps the two in step.
+type scheduledQ struct {
+	w               interleave.Word
+	closed          interleave.Word
+	queueSizeFactor int
+}
+
+func (q scheduledQ) Pop() (int, uint32, bool) {
+	closed := q.closed.Load()
+	pos, savepoint, isEmpty := popAt(q.w.Load(), q.queueSizeFactor)
+	if isEmpty && closed != 0 {
+		return Closed, 0, true
+	}
+
+	return pos, savepoint, isEmpty
+}
+
+func (q scheduledQ) PopCommit(savepoint uint32) bool {
+	return q.w.CompareAndSwap(savepoint, uint32(savepoint+consts.CommitPopU32))
+}
+
+func (q scheduledQ) Push() (int, bool) {
+	if q.closed.Load() != 0 {
+		return Closed, true
+	}
+
+	acquired := q.w.Load()
+	if acquired&consts.PushOverflowCheckU32 != 0 {
+		q.w.Add(consts.PushOverflowProtectionU32)
+	}
+
+	return pushAt(acquired, q.queueSizeFactor)
+}
+
+func (q scheduledQ) PushCommit() {
+	q.w.Add(1)
+}
+
+func (q scheduledQ) Close() {
+	q.closed.Store(1)
+}
+
+func TestInterleavings(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		queueSizeFactor int
+		jobs            int
+		consumers       int
+		attempts        int
+		state           uint32
+		close           bool
+	}{
+		{
+			desc:            "Two consumers race for a single job before the queue is closed",
+			queueSizeFactor: 1,
+			state:           0,
+			jobs:            1,
+			consumers:       2,
+			attempts:        1,
+			close:           true,
+		},
+		{
+			desc:            "Two consumers race for a job at the end of the ring before the queue is closed",
+			queueSizeFactor: 1,
+			state:           0x00010001,
+			jobs:            1,
+			consumers:       2,
+			attempts:        1,
+			close:           true,
+		},
+		{
+			desc:            "Two consumers race for jobs that wrap around the ring before the queue is closed",
+			queueSizeFactor: 1,
+			state:           0x00010001,
+			jobs:            2,
+			consumers:       2,
+			attempts:        1,
+			close:           true,
+		},
+		{
+			desc:            "Two consumers race for jobs that wrap around the ring",
+			queueSizeFactor: 1,
+			state:           0x00010001,
+			jobs:            2,
+			consumers:       2,
+			attempts:        2,
+		},
+		{
+			desc:            "Two consumers race for jobs while the producer fills the ring",
+			queueSizeFactor: 2,
+			state:           0,
+			jobs:            3,
+			consumers:       2,
+			attempts:        1,
+		},
+		{
+			desc:            "Two consumers race for jobs while the head is protected from overflowing",
+			queueSizeFactor: 2,
+			state:           0x7fff7fff,
+			jobs:            2,
+			consumers:       2,
+			attempts:        1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			runs, err := interleave.Explore(func(s *interleave.Scheduler) func() error {
+				// The goroutines run the operations of Q on a scheduled state word. The closed word is only scheduled
+				// when the queue is closed, since a word that never changes only multiplies the interleavings.
+				q := scheduledQ{w: s.NewWord(tC.state), closed: new(interleave.Atomic), queueSizeFactor: tC.queueSizeFactor}
+				if tC.close {
+					q.closed = s.NewWord(0)
+				}
+				slots := make([]int, 1<<tC.queueSizeFactor)
+				pushed := 0
+				var delivered []int
+				var closedEarly error
+
+				// Producer, which gives up on a job after a bounded number of attempts, so that every schedule ends
+				s.Go(func() {
+					if tC.close {
+						defer q.Close()
+					}
+
+					for job := 1; job <= tC.jobs; job++ {
+						for attempt := 0; attempt < tC.attempts; attempt++ {
+							pos, isFull := q.Push()
+							if isFull {
+								continue
+							}
+
+							s.Yield()
+							slots[pos] = job
+							pushed++
+							q.PushCommit()
+							break
+						}
+					}
+				})
+
+				// Consumers
+				for c := 0; c < tC.consumers; c++ {
+					s.Go(func() {
+						for attempt := 0; attempt < tC.attempts; attempt++ {
+							pos, savepoint, isEmpty := q.Pop()
+							if isEmpty {
+								if pos == Closed {
+									// Closed is only returned once every job that was pushed has been delivered
+									if len(delivered) != pushed && closedEarly == nil {
+										closedEarly = fmt.Errorf("expected %d jobs to be delivered before the closed result, got %d", pushed, len(delivered))
+									}
+									return
+								}
+								continue
+							}
+
+							s.Yield()
+							job := slots[pos]
+							if !q.PopCommit(savepoint) {
+								continue // Commit failed so we can't run the job
+							}
+
+							delivered = append(delivered, job)
+						}
+					})
+				}
+
+				return func() error {
+					if closedEarly != nil {
+						return closedEarly
+					}
+
+					// Jobs are pushed in order, so the committed pops must deliver them in order, each exactly once
+					for i, job := range delivered {
+						if job != i+1 {
+							return fmt.Errorf("expected delivery number %d to be job %d, got job %d (deliveries %v)", i, i+1, job, delivered)
+						}
+					}
+
+					left := 0
+					for pos, savepoint, isEmpty := q.Pop(); !isEmpty; pos, savepoint, isEmpty = q.Pop() {
+						if slots[pos] != len(delivered)+left+1 {
+							return fmt.Errorf("expected the job left at position %d to be %d, got %d", pos, len(delivered)+left+1, slots[pos])
+						}
+						q.PopCommit(savepoint)
+						left++
+					}
+
+					if len(delivered)+left != pushed {
+						return fmt.Errorf("expected %d jobs to be delivered or left in the queue, got %d delivered and %d left", pushed, len(delivered), left)
+					}
+					return nil
+				}
+			})
+			if err != nil {
+				subT.Errorf("unexpected error after %d interleavings: %v", runs, err)
+			}
+
+			subT.Logf("explored %d interleavings", runs)
+		})
+	}
+}
+
+func TestScheduledQMatchesQ(t *testing.T) {
+	const queueSizeFactor = 2
+	const depth = 6
+	ops := []string{"Push", "PushCommit", "Pop", "PopCommit", "Close"}
+	testCases := []struct {
+		desc  string
+		state uint32
+	}{
+		{
+			desc:  "Empty queue",
+			state: 0,
+		},
+		{
+			desc:  "Queue at the end of the ring",
+			state: 0x00030003,
+		},
+		{
+			desc:  "Head about to be protected from overflowing",
+			state: 0x7fff7ffe,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			sequences := 1
+			for i := 0; i < depth; i++ {
+				sequences *= len(ops)
+			}
+
+			// Every sequence of operations must return the same results and leave the same state on both queues
+			for seq := 0; seq < sequences; seq++ {
+				q := NewQ(queueSizeFactor)
+				q.q = tC.state
+				w := interleave.Atomic(tC.state)
+				sq := scheduledQ{w: &w, closed: new(interleave.Atomic), queueSizeFactor: queueSizeFactor}
+				var savepoint, scheduledSavepoint uint32
+				var run []string
+
+				for i, op := 0, seq; i < depth; i, op = i+1, op/len(ops) {
+					run = append(run, ops[op%len(ops)])
+
+					var expected, got string
+					switch op % len(ops) {
+					case 0:
+						expected, got = fmt.Sprint(q.Push()), fmt.Sprint(sq.Push())
+					case 1:
+						q.PushCommit()
+						sq.PushCommit()
+					case 2:
+						var pos, scheduledPos int
+						var isEmpty, scheduledIsEmpty bool
+						pos, savepoint, isEmpty = q.Pop()
+						scheduledPos, scheduledSavepoint, scheduledIsEmpty = sq.Pop()
+						expected, got = fmt.Sprint(pos, savepoint, isEmpty), fmt.Sprint(scheduledPos, scheduledSavepoint, scheduledIsEmpty)
+					case 3:
+						expected, got = fmt.Sprint(q.PopCommit(savepoint)), fmt.Sprint(sq.PopCommit(scheduledSavepoint))
+					case 4:
+						q.Close()
+						sq.Close()
+					}
+
+					if expected != got || q.q != w.Load() || q.closed != sq.closed.Load() {
+						subT.Fatalf("expected %v to return %s with state %#x and closed %d, got %s with state %#x and closed %d",
+							run, expected, q.q, q.closed, got, w.Load(), sq.closed.Load())
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package micro
+
+import (
+	"unsafe"
+
+	"github.com/probably-not/q/internal/check"
+)
+
+// cacheLineSize is the size of a cache line on the common 64 bit platforms.
+const cacheLineSize = 64
+
+// PaddedQ is a `micro.Q` that is padded so that its state word sits on a cache line of its own.
+// When queues are placed in a slice, or next to other fields that are written often, the state words of neighbouring
+// queues share cache lines, and every commit to one queue invalidates the line for the cores using its neighbours,
+// which is called false sharing. A PaddedQ trades 128 bytes of memory per queue for isolating its state word.
+// The head and tail stay packed in the same state word, so a PaddedQ has the exact same protocol as a `micro.Q`,
+// and every method of `micro.Q` is available on it.
+type PaddedQ struct {
+	_ [cacheLineSize]byte
+	Q
+	_ [cacheLineSize - unsafe.Sizeof(Q{})%cacheLineSize]byte
+}
+
+func NewPaddedQ(queueSizeFactor int) *PaddedQ {
+	return &PaddedQ{
+		Q: Q{
+			q:               0,
+			queueSizeFactor: queueSizeFactor,
+		},
+	}
+}
+
+// NewPaddedQChecked creates a new padded queue after validating the size factor.
+// If the factor can't be used with the queue, ErrFactorZero or ErrFactorTooLarge is returned.
+func NewPaddedQChecked(queueSizeFactor int) (*PaddedQ, error) {
+	if err := check.FactorU32(queueSizeFactor); err != nil {
+		return nil, err
+	}
+
+	return NewPaddedQ(queueSizeFactor), nil
+}
+
+// InitPadded initializes a slice of padded queues in place, which is how padded queues are meant to be allocated
+// when there are many of them, since a slice of PaddedQ values keeps every state word on a line of its own.
+func InitPadded(queues []PaddedQ, queueSizeFactor int) {
+	for i := range queues {
+		queues[i].Q = Q{
+			q:               0,
+			queueSizeFactor: queueSizeFactor,
+		}
+	}
+}
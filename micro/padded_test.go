@@ -0,0 +1,74 @@
+package micro
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestPaddedQLayout(t *testing.T) {
+	queues := make([]PaddedQ, 3)
+	InitPadded(queues, 6)
+
+	for i := 1; i < len(queues); i++ {
+		prev := uintptr(unsafe.Pointer(&queues[i-1].q))
+		next := uintptr(unsafe.Pointer(&queues[i].q))
+		if next-prev < 2*cacheLineSize {
+			t.Errorf("expected the state words of neighbouring queues to be at least %d bytes apart, got %d", 2*cacheLineSize, next-prev)
+		}
+	}
+
+	offset := unsafe.Offsetof(PaddedQ{}.Q)
+	if offset < cacheLineSize {
+		t.Errorf("expected the queue to be padded by at least %d bytes, got %d", cacheLineSize, offset)
+	}
+
+	if size := unsafe.Sizeof(PaddedQ{}); size%cacheLineSize != 0 {
+		t.Errorf("expected the padded queue to fill whole cache lines, got %d bytes", size)
+	}
+}
+
+func TestPaddedQ(t *testing.T) {
+	testCases := []struct {
+		queue *PaddedQ
+		desc  string
+	}{
+		{
+			desc:  "Queue created with NewPaddedQ",
+			queue: NewPaddedQ(6),
+		},
+		{
+			desc: "Queue initialized with InitPadded",
+			queue: func() *PaddedQ {
+				queues := make([]PaddedQ, 1)
+				InitPadded(queues, 6)
+				return &queues[0]
+			}(),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q := tC.queue
+			for i := 0; i < 100; i++ {
+				idx, isFull := q.Push()
+				if isFull {
+					subT.Errorf("unexpected full queue at push number %d", i)
+					return
+				}
+
+				if i%(1<<6) != idx {
+					subT.Errorf("expected pushed job to be %d but got %d", i%(1<<6), idx)
+				}
+				q.PushCommit()
+
+				idx, savepoint, isEmpty := q.Pop()
+				if isEmpty || i%(1<<6) != idx {
+					subT.Errorf("expected popped job to be %d but got %d (empty %t)", i%(1<<6), idx, isEmpty)
+				}
+
+				if !q.PopCommit(savepoint) {
+					subT.Errorf("expected pop commit to pass normally on job %d but got commit failed", i)
+				}
+			}
+		})
+	}
+}
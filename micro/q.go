@@ -1,11 +1,29 @@
 package micro
 
 import (
+	"errors"
+	"fmt"
 	"sync/atomic"
 
+	"github.com/probably-not/q/internal/check"
 	"github.com/probably-not/q/internal/consts"
 )
 
+var (
+	// ErrFactorTooLarge is returned when the size factor does not fit in the halves of the queue state.
+	ErrFactorTooLarge = check.ErrFactorTooLarge
+	// ErrFactorZero is returned when the size factor is zero or negative.
+	ErrFactorZero = check.ErrFactorZero
+)
+
+// Closed is the position returned by Push once the queue has been closed, and by Pop once the queue has been
+// closed and fully drained. It is returned along with `true`, so callers that only check the boolean treat a
+// closed queue as full or empty.
+const Closed = -2
+
+// ErrClosed is returned by the blocking operations once the queue has been closed (and, for pops, drained).
+var ErrClosed = errors.New("queue is closed")
+
 type noCopy struct{}
 
 func (*noCopy) Lock()   {}
@@ -14,35 +32,58 @@ func (*noCopy) Unlock() {}
 type Q struct {
 	noCopy
 	q               uint32
+	closed          uint32
+	protections     uint32
 	queueSizeFactor int
 }
 
 func NewQ(queueSizeFactor int) *Q {
 	return &Q{
 		q:               0,
+		closed:          0,
 		queueSizeFactor: queueSizeFactor,
 	}
 }
 
+// NewQChecked creates a new queue after validating the size factor.
+// If the factor can't be used with the queue, ErrFactorZero or ErrFactorTooLarge is returned.
+func NewQChecked(queueSizeFactor int) (*Q, error) {
+	if err := check.FactorU32(queueSizeFactor); err != nil {
+		return nil, err
+	}
+
+	return NewQ(queueSizeFactor), nil
+}
+
 // Pop will calculate the position that can currently be popped from the queue.
 // It returns the position, a save point (to allow ensuring the commit),
 // along with a boolean indicating if the queue is empty or not.
 // If the queue is empty, `-1, 0, true` will be returned.
+// If the queue is closed and empty, `Closed, 0, true` will be returned.
 // If the queue is not empty, `pos, savepoint, false` will be returned.
 // After receiving the position and savepoint, PopCommit must be called in
 // order to ensure that the job is truly the caller's job, and it has not been
 // committed by another consumer.
-func (q *Q) Pop(factor int) (int, uint32, bool) {
-	acquired := atomic.LoadUint32(&q.q)
-	mask := (uint32(1) << factor) - 1
-	head := acquired & mask
-	tail := acquired >> 16 & mask
-
-	if head == tail {
-		return -1, 0, true
+func (q *Q) Pop() (int, uint32, bool) {
+	check.AssertFactorU32(q.queueSizeFactor)
+	// The closed flag is loaded before the queue, so that a commit made before closing is always seen
+	closed := atomic.LoadUint32(&q.closed)
+	pos, savepoint, isEmpty := popAt(atomic.LoadUint32(&q.q), q.queueSizeFactor)
+	if isEmpty && closed != 0 {
+		return Closed, 0, true
 	}
 
-	return int(tail), acquired, false
+	return pos, savepoint, isEmpty
+}
+
+// PopWithFactor is the previous generation of Pop, which received the size factor from the caller.
+// It panics if the factor does not match the size factor that the queue was created with,
+// since a mismatched factor silently corrupts the positions that are returned.
+//
+// Deprecated: use Pop, which reads the size factor from the queue.
+func (q *Q) PopWithFactor(factor int) (int, uint32, bool) {
+	q.mustMatchFactor(factor)
+	return q.Pop()
 }
 
 // PopCommit will commit the previously executed Pop operation to the queue.
@@ -58,18 +99,124 @@ func (q *Q) PopCommit(savepoint uint32) bool {
 // Push will calculate the position that can currently be pushed to in the queue.
 // It returns the position, along with a boolean indicating if the queue is full or not.
 // If the queue is full, `-1, true` will be returned.
+// If the queue is closed, `Closed, true` will be returned.
 // If the queue is not full, `pos, false` will be returned.
-func (q *Q) Push(factor int) (int, bool) {
+func (q *Q) Push() (int, bool) {
+	check.AssertFactorU32(q.queueSizeFactor)
+	if atomic.LoadUint32(&q.closed) != 0 {
+		return Closed, true
+	}
+
+	acquired := atomic.LoadUint32(&q.q)
+	if acquired&consts.PushOverflowCheckU32 != 0 {
+		atomic.AddUint32(&q.q, consts.PushOverflowProtectionU32)
+		atomic.AddUint32(&q.protections, 1)
+	}
+
+	return pushAt(acquired, q.queueSizeFactor)
+}
+
+// PushCommit will commit the previously executed Push operation to the queue.
+// This moves the index of the queue to the next push-able index.
+func (q *Q) PushCommit() {
+	atomic.AddUint32(&q.q, 1)
+}
+
+// Len returns the number of jobs that are committed to the queue and not yet popped.
+// It is decoded from a single atomic load, and unlike a Push probe it has no side effects.
+func (q *Q) Len() int {
+	check.AssertFactorU32(q.queueSizeFactor)
+	acquired := atomic.LoadUint32(&q.q)
+	mask := (uint32(1) << q.queueSizeFactor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	return int((head - tail) & mask)
+}
+
+// Cap returns the number of jobs that the queue can hold, which is one less than its number of positions,
+// since a full queue always keeps one position free to tell it apart from an empty queue.
+func (q *Q) Cap() int {
+	check.AssertFactorU32(q.queueSizeFactor)
+	return (1 << q.queueSizeFactor) - 1
+}
+
+// IsEmpty reports whether the queue is empty, from a single atomic load and without side effects.
+func (q *Q) IsEmpty() bool {
+	check.AssertFactorU32(q.queueSizeFactor)
+	acquired := atomic.LoadUint32(&q.q)
+	mask := (uint32(1) << q.queueSizeFactor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	return head == tail
+}
+
+// IsFull reports whether the queue is full, from a single atomic load and without side effects.
+func (q *Q) IsFull() bool {
+	check.AssertFactorU32(q.queueSizeFactor)
 	acquired := atomic.LoadUint32(&q.q)
+	mask := (uint32(1) << q.queueSizeFactor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	return (head+uint32(1))&mask == tail
+}
+
+// Close will close the queue, after which Push no longer accepts jobs, and Pop drains the
+// jobs that were already committed before reporting that the queue is closed.
+// Close should be called by the producer (or once the producer has stopped), since a job
+// that is committed concurrently with Close may never be drained. Closing more than once is a no-op.
+// Since the queue holds no waiters, Close doesn't wake consumers that are parked in PopWait: the caller must call
+// Notify on their WaitStrategy after Close, and a consumer that wakes up to `micro.Closed` should call Notify again,
+// so that a single notification is passed along to every parked consumer.
+func (q *Q) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+}
+
+// IsClosed reports whether Close has been called on the queue.
+func (q *Q) IsClosed() bool {
+	return atomic.LoadUint32(&q.closed) != 0
+}
+
+// OverflowProtections returns the number of times that Push or PushN has protected the head of the queue from
+// overflowing into the tail, which happens once every 2^15 pushes. It is meant for instrumentation, such as the
+// `metrics` package, and wraps around once it no longer fits in a `uint32`.
+func (q *Q) OverflowProtections() uint32 {
+	return atomic.LoadUint32(&q.protections)
+}
+
+// PushWithFactor is the previous generation of Push, which received the size factor from the caller.
+// It panics if the factor does not match the size factor that the queue was created with,
+// since a mismatched factor silently corrupts the positions that are returned.
+//
+// Deprecated: use Push, which reads the size factor from the queue.
+func (q *Q) PushWithFactor(factor int) (int, bool) {
+	q.mustMatchFactor(factor)
+	return q.Push()
+}
+
+// popAt decodes the position that can be popped from an acquired state of the queue.
+// It is kept apart from the atomic operations so that the interleaving tests run the same decoding as Pop.
+func popAt(acquired uint32, factor int) (int, uint32, bool) {
 	mask := (uint32(1) << factor) - 1
 	head := acquired & mask
 	tail := acquired >> 16 & mask
-	next := (head + uint32(1)) & mask
 
-	if acquired&consts.PushOverflowCheckU32 != 0 {
-		atomic.AddUint32(&q.q, consts.PushOverflowProtectionU32)
+	if head == tail {
+		return -1, 0, true
 	}
 
+	return int(tail), acquired, false
+}
+
+// pushAt decodes the position that can be pushed to from an acquired state of the queue.
+func pushAt(acquired uint32, factor int) (int, bool) {
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+	next := (head + uint32(1)) & mask
+
 	if next == tail {
 		return -1, true
 	}
@@ -77,8 +224,8 @@ func (q *Q) Push(factor int) (int, bool) {
 	return int(head), false
 }
 
-// PushCommit will commit the previously executed Push operation to the queue.
-// This moves the index of the queue to the next push-able index.
-func (q *Q) PushCommit() {
-	atomic.AddUint32(&q.q, 1)
+func (q *Q) mustMatchFactor(factor int) {
+	if factor != q.queueSizeFactor {
+		panic(fmt.Sprintf("micro: factor %d does not match the queue size factor %d", factor, q.queueSizeFactor))
+	}
 }
@@ -1,6 +1,7 @@
 package micro
 
 import (
+	"errors"
 	"math/rand"
 	"sync"
 	"sync/atomic"
@@ -14,7 +15,6 @@ func TestPop(t *testing.T) {
 		desc                string
 		expectedAllowedPops int
 		expectedIdx         int
-		queueSizeFactor     int
 		expectedIsEmpty     bool
 	}{
 		{
@@ -22,7 +22,6 @@ func TestPop(t *testing.T) {
 			queue:           NewQ(6),
 			expectedIdx:     -1,
 			expectedIsEmpty: true,
-			queueSizeFactor: 6,
 		},
 		{
 			desc: "Pops are allowed as many times as there are jobs in the queue, and when completed return empty",
@@ -36,7 +35,6 @@ func TestPop(t *testing.T) {
 			expectedAllowedPops: 10,
 			expectedIdx:         -1,
 			expectedIsEmpty:     true,
-			queueSizeFactor:     6,
 		},
 		{
 			desc: "Pops are allowed as many times as there are jobs in the queue, and when not completed return the next index",
@@ -50,13 +48,12 @@ func TestPop(t *testing.T) {
 			expectedAllowedPops: 8,
 			expectedIdx:         8,
 			expectedIsEmpty:     false,
-			queueSizeFactor:     6,
 		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(subT *testing.T) {
 			for i := 0; i < tC.expectedAllowedPops; i++ {
-				idx, savepoint, isEmpty := tC.queue.Pop(tC.queueSizeFactor)
+				idx, savepoint, isEmpty := tC.queue.Pop()
 				if idx == -1 || isEmpty {
 					subT.Errorf("unexpected empty queue during allowed pops at pop number %d", i)
 					return
@@ -71,7 +68,7 @@ func TestPop(t *testing.T) {
 				}
 			}
 
-			idx, _, isEmpty := tC.queue.Pop(tC.queueSizeFactor)
+			idx, _, isEmpty := tC.queue.Pop()
 			if tC.expectedIdx != idx {
 				subT.Errorf("expected the returned index to be %d, got %d", tC.expectedIdx, idx)
 			}
@@ -91,7 +88,6 @@ func TestPush(t *testing.T) {
 		desc               string
 		expectedIdx        int
 		nextExpectedIdx    int
-		queueSizeFactor    int
 		expectedIsFull     bool
 		nextExpectedIsFull bool
 	}{
@@ -102,7 +98,6 @@ func TestPush(t *testing.T) {
 			nextExpectedIsFull: false,
 			expectedIdx:        0,
 			nextExpectedIdx:    1,
-			queueSizeFactor:    6,
 		},
 		{
 			desc:               "Queue with random amount of jobs less than size factor allows pushing",
@@ -111,7 +106,6 @@ func TestPush(t *testing.T) {
 			nextExpectedIsFull: false,
 			expectedIdx:        randomAmountOfJobs,
 			nextExpectedIdx:    randomAmountOfJobs + 1,
-			queueSizeFactor:    6,
 		},
 		{
 			desc:               "Queue at the size factor is marked as full and cannot be pushed to",
@@ -120,7 +114,6 @@ func TestPush(t *testing.T) {
 			nextExpectedIsFull: false,
 			expectedIdx:        -1,
 			nextExpectedIdx:    0,
-			queueSizeFactor:    6,
 		},
 		{
 			desc:               "Overflow is protected against",
@@ -129,12 +122,11 @@ func TestPush(t *testing.T) {
 			nextExpectedIsFull: false,
 			expectedIdx:        63,
 			nextExpectedIdx:    0,
-			queueSizeFactor:    6,
 		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(subT *testing.T) {
-			idx, isFull := tC.queue.Push(tC.queueSizeFactor)
+			idx, isFull := tC.queue.Push()
 			if tC.expectedIdx != idx {
 				subT.Errorf("expected the returned index to be %d, got %d", tC.expectedIdx, idx)
 			}
@@ -145,7 +137,7 @@ func TestPush(t *testing.T) {
 
 			tC.queue.PushCommit()
 
-			idx, isFull = tC.queue.Push(tC.queueSizeFactor)
+			idx, isFull = tC.queue.Push()
 			if tC.nextExpectedIdx != idx {
 				subT.Errorf("expected the next returned index to be %d, got %d", tC.nextExpectedIdx, idx)
 			}
@@ -157,6 +149,61 @@ func TestPush(t *testing.T) {
 	}
 }
 
+func TestOverflowProtections(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		batch    int
+		pushes   int
+		expected uint32
+	}{
+		{
+			desc:     "No protection before the head reaches the overflow check",
+			batch:    1,
+			pushes:   0x8000,
+			expected: 0,
+		},
+		{
+			desc:     "Single pushes are protected once every 2^15 pushes",
+			batch:    1,
+			pushes:   0x10001,
+			expected: 2,
+		},
+		{
+			desc:     "Batch pushes are protected once every 2^15 pushes",
+			batch:    8,
+			pushes:   0x10001,
+			expected: 2,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q := NewQ(6)
+			for pushed := 0; pushed < tC.pushes; {
+				n := tC.batch
+				if tC.pushes-pushed < n {
+					n = tC.pushes - pushed
+				}
+
+				if n == 1 {
+					q.Push()
+					q.PushCommit()
+				} else {
+					_, n = q.PushN(n)
+					q.PushCommitN(n)
+				}
+				pushed += n
+
+				_, count, savepoint := q.PopN(n)
+				q.PopCommitN(savepoint, count)
+			}
+
+			if protections := q.OverflowProtections(); protections != tC.expected {
+				subT.Errorf("expected the overflow protections to be %d, got %d", tC.expected, protections)
+			}
+		})
+	}
+}
+
 func TestConcurrentWorkSingleConsumer(t *testing.T) {
 	const queueSizeFactor = 6
 	q := NewQ(queueSizeFactor)
@@ -177,7 +224,7 @@ func TestConcurrentWorkSingleConsumer(t *testing.T) {
 		fullAttempts := 0
 
 		for i := 0; i < 1000; i++ {
-			slot, isFull := q.Push(queueSizeFactor)
+			slot, isFull := q.Push()
 			if isFull {
 				fullAttempts++
 				if fullAttempts > 1000 {
@@ -199,7 +246,7 @@ func TestConcurrentWorkSingleConsumer(t *testing.T) {
 		defer wg.Done()
 
 		for {
-			slot, savepoint, isEmpty := q.Pop(queueSizeFactor)
+			slot, savepoint, isEmpty := q.Pop()
 			if isEmpty {
 				if atomic.LoadInt32(&completedProducing) > 0 {
 					break
@@ -245,7 +292,7 @@ func TestConcurrentWorkMultipleConsumers(t *testing.T) {
 		fullAttempts := 0
 
 		for i := int64(0); i < 1000; i++ {
-			slot, isFull := q.Push(queueSizeFactor)
+			slot, isFull := q.Push()
 			if isFull {
 				fullAttempts++
 				if fullAttempts > 1000 {
@@ -274,7 +321,7 @@ func TestConcurrentWorkMultipleConsumers(t *testing.T) {
 			defer wg.Done()
 
 			for {
-				slot, savepoint, isEmpty := q.Pop(queueSizeFactor)
+				slot, savepoint, isEmpty := q.Pop()
 				if isEmpty {
 					if atomic.LoadInt32(&completedProducing) > 0 {
 						break
@@ -304,3 +351,152 @@ func TestConcurrentWorkMultipleConsumers(t *testing.T) {
 		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
 	}
 }
+
+func TestWithFactor(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		queueSizeFactor int
+		expectedPanic   bool
+	}{
+		{
+			desc:            "Matching factor behaves like the factor-free operations",
+			queueSizeFactor: 6,
+			expectedPanic:   false,
+		},
+		{
+			desc:            "Mismatched factor panics instead of returning corrupted positions",
+			queueSizeFactor: 7,
+			expectedPanic:   true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q := NewQ(6)
+
+			defer func() {
+				r := recover()
+				if tC.expectedPanic != (r != nil) {
+					subT.Errorf("expected panic to be %t, got %v", tC.expectedPanic, r)
+				}
+			}()
+
+			idx, isFull := q.PushWithFactor(tC.queueSizeFactor)
+			if idx != 0 || isFull {
+				subT.Errorf("expected push to return 0 and not full, got %d and %t", idx, isFull)
+			}
+			q.PushCommit()
+
+			idx, _, isEmpty := q.PopWithFactor(tC.queueSizeFactor)
+			if idx != 0 || isEmpty {
+				subT.Errorf("expected pop to return 0 and not empty, got %d and %t", idx, isEmpty)
+			}
+		})
+	}
+}
+
+func TestNewQChecked(t *testing.T) {
+	testCases := []struct {
+		expectedErr     error
+		desc            string
+		queueSizeFactor int
+	}{
+		{
+			desc:            "Factor within the supported range is accepted",
+			queueSizeFactor: 6,
+			expectedErr:     nil,
+		},
+		{
+			desc:            "Largest supported factor is accepted",
+			queueSizeFactor: 15,
+			expectedErr:     nil,
+		},
+		{
+			desc:            "Factor larger than the 16 bit halves allow is rejected",
+			queueSizeFactor: 16,
+			expectedErr:     ErrFactorTooLarge,
+		},
+		{
+			desc:            "Zero factor is rejected",
+			queueSizeFactor: 0,
+			expectedErr:     ErrFactorZero,
+		},
+		{
+			desc:            "Negative factor is rejected",
+			queueSizeFactor: -1,
+			expectedErr:     ErrFactorZero,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			_, err := NewQChecked(tC.queueSizeFactor)
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	testCases := []struct {
+		queue           *Q
+		desc            string
+		expectedLen     int
+		expectedIsEmpty bool
+		expectedIsFull  bool
+	}{
+		{
+			desc:            "Zero value of queue is empty",
+			queue:           &Q{q: 0, queueSizeFactor: 6},
+			expectedLen:     0,
+			expectedIsEmpty: true,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue with jobs reports their count",
+			queue:           &Q{q: 10, queueSizeFactor: 6},
+			expectedLen:     10,
+			expectedIsEmpty: false,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue at the size factor is full",
+			queue:           &Q{q: 63, queueSizeFactor: 6},
+			expectedLen:     63,
+			expectedIsEmpty: false,
+			expectedIsFull:  true,
+		},
+		{
+			desc:            "Queue that has wrapped around reports the jobs across the boundary",
+			queue:           &Q{q: 60<<16 | 4, queueSizeFactor: 6},
+			expectedLen:     8,
+			expectedIsEmpty: false,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue that has overflowed is empty",
+			queue:           &Q{q: 4294967295, queueSizeFactor: 6},
+			expectedLen:     0,
+			expectedIsEmpty: true,
+			expectedIsFull:  false,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			if l := tC.queue.Len(); tC.expectedLen != l {
+				subT.Errorf("expected the length to be %d, got %d", tC.expectedLen, l)
+			}
+
+			if c := tC.queue.Cap(); c != 63 {
+				subT.Errorf("expected the capacity to be %d, got %d", 63, c)
+			}
+
+			if isEmpty := tC.queue.IsEmpty(); tC.expectedIsEmpty != isEmpty {
+				subT.Errorf("expected isEmpty to be %t, got %t", tC.expectedIsEmpty, isEmpty)
+			}
+
+			if isFull := tC.queue.IsFull(); tC.expectedIsFull != isFull {
+				subT.Errorf("expected isFull to be %t, got %t", tC.expectedIsFull, isFull)
+			}
+		})
+	}
+}
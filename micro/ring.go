@@ -0,0 +1,92 @@
+package micro
+
+import (
+	"sync/atomic"
+
+	"github.com/probably-not/q/internal/check"
+)
+
+// Ring is a typed wrapper around `micro.Q` which owns the slice of jobs that the queue indexes into.
+// It removes the need for callers to allocate their own `[1<<factor]T` array and to manage
+// the Pop/PopCommit savepoint retry themselves.
+// Ring keeps the same guarantee as `micro.Q`: it is safe for a single producer with multiple consumers.
+// Since a consumer that loses its commit may read a slot while the producer is writing to it,
+// the slots are loaded and stored atomically, so a losing consumer never sees a torn value.
+// The atomic slots hold their values boxed in an interface, so unlike `micro.Q`, every push of a value that
+// isn't pointer shaped, like an `int` or a `string`, allocates once. Pointer shaped values, like pointers, maps,
+// channels and funcs, are stored in the interface as is and don't allocate, so callers on an allocation free
+// path can push pointers to values that they own, or use `micro.Q` directly with their own slots.
+type Ring[T any] struct {
+	slots []atomic.Value
+	Q
+}
+
+// boxed is the type stored in the atomic slots, so that every slot holds the same type even when T is an interface.
+type boxed[T any] struct {
+	v T
+}
+
+func NewRing[T any](queueSizeFactor int) *Ring[T] {
+	r := &Ring[T]{}
+	r.init(queueSizeFactor)
+	return r
+}
+
+// NewRingChecked creates a new ring after validating the size factor.
+// If the factor can't be used with the ring, ErrFactorZero or ErrFactorTooLarge is returned.
+func NewRingChecked[T any](queueSizeFactor int) (*Ring[T], error) {
+	if err := check.FactorU32(queueSizeFactor); err != nil {
+		return nil, err
+	}
+
+	return NewRing[T](queueSizeFactor), nil
+}
+
+func (r *Ring[T]) init(queueSizeFactor int) {
+	r.slots = make([]atomic.Value, 1<<queueSizeFactor)
+	r.Q = Q{
+		q:               0,
+		queueSizeFactor: queueSizeFactor,
+	}
+}
+
+// TryPush will attempt to push the value to the ring.
+// It returns `true` if the value was pushed, or `false` if the ring is full or closed.
+func (r *Ring[T]) TryPush(v T) bool {
+	pos, isFull := r.Push()
+	if isFull {
+		return false
+	}
+
+	r.store(pos, v)
+	r.PushCommit()
+	return true
+}
+
+// TryPop will attempt to pop a value from the ring.
+// It returns the value along with `true` if a value was popped, or the zero value of `T`
+// along with `false` if the ring is empty, or closed and drained.
+// If another consumer commits the same position first, TryPop retries until it either
+// commits a position of its own or finds the ring empty.
+func (r *Ring[T]) TryPop() (T, bool) {
+	for {
+		pos, savepoint, isEmpty := r.Pop()
+		if isEmpty {
+			var zero T
+			return zero, false
+		}
+
+		v := r.load(pos)
+		if r.PopCommit(savepoint) {
+			return v, true
+		}
+	}
+}
+
+func (r *Ring[T]) store(pos int, v T) {
+	r.slots[pos].Store(boxed[T]{v: v})
+}
+
+func (r *Ring[T]) load(pos int) T {
+	return r.slots[pos].Load().(boxed[T]).v
+}
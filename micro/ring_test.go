@@ -0,0 +1,239 @@
+package micro
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRingTryPushTryPop(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		pushes            int
+		expectedPushed    int
+		pops              int
+		expectedPopped    int
+		queueSizeFactor   int
+		expectedLastValue int
+	}{
+		{
+			desc:            "Zero pushes leaves the ring empty",
+			pushes:          0,
+			expectedPushed:  0,
+			pops:            1,
+			expectedPopped:  0,
+			queueSizeFactor: 6,
+		},
+		{
+			desc:              "Values are popped in the order they were pushed",
+			pushes:            10,
+			expectedPushed:    10,
+			pops:              10,
+			expectedPopped:    10,
+			queueSizeFactor:   6,
+			expectedLastValue: 9,
+		},
+		{
+			desc:              "Pushes past the capacity of the ring are rejected",
+			pushes:            100,
+			expectedPushed:    63,
+			pops:              100,
+			expectedPopped:    63,
+			queueSizeFactor:   6,
+			expectedLastValue: 62,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			r := NewRing[int](tC.queueSizeFactor)
+
+			pushed := 0
+			for i := 0; i < tC.pushes; i++ {
+				if r.TryPush(i) {
+					pushed++
+				}
+			}
+
+			if tC.expectedPushed != pushed {
+				subT.Errorf("expected %d values to be pushed, got %d", tC.expectedPushed, pushed)
+			}
+
+			popped := 0
+			last := -1
+			for i := 0; i < tC.pops; i++ {
+				v, ok := r.TryPop()
+				if !ok {
+					if v != 0 {
+						subT.Errorf("expected the zero value on an empty ring, got %d", v)
+					}
+					continue
+				}
+
+				if v != popped {
+					subT.Errorf("expected popped value to be %d but got %d", popped, v)
+				}
+				popped++
+				last = v
+			}
+
+			if tC.expectedPopped != popped {
+				subT.Errorf("expected %d values to be popped, got %d", tC.expectedPopped, popped)
+			}
+
+			if tC.expectedPopped > 0 && tC.expectedLastValue != last {
+				subT.Errorf("expected the last popped value to be %d, got %d", tC.expectedLastValue, last)
+			}
+		})
+	}
+}
+
+func TestRingConcurrentWork(t *testing.T) {
+	const queueSizeFactor = 6
+	r := NewRing[int](queueSizeFactor)
+
+	var wg sync.WaitGroup
+	wg.Add(2) // Add producer and consumer goroutines
+
+	// Producer
+	producedSum := 0
+	completedProducing := int32(0)
+	go func() {
+		defer func() {
+			atomic.AddInt32(&completedProducing, 1)
+			wg.Done()
+		}()
+
+		for i := 0; i < 1000; i++ {
+			for !r.TryPush(i) {
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+			}
+			producedSum += i
+		}
+	}()
+
+	// Consumer
+	sum := 0
+	go func() {
+		defer wg.Done()
+
+		for {
+			job, ok := r.TryPop()
+			if !ok {
+				if atomic.LoadInt32(&completedProducing) > 0 {
+					// Drain anything committed after our last empty check
+					for job, ok = r.TryPop(); ok; job, ok = r.TryPop() {
+						sum += job
+					}
+					break
+				}
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				continue
+			}
+
+			sum += job
+		}
+	}()
+
+	wg.Wait()
+
+	if producedSum != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+}
+
+func TestRingConcurrentConsumers(t *testing.T) {
+	const queueSizeFactor = 4
+	const consumers = 4
+	r := NewRing[int](queueSizeFactor)
+
+	var wg sync.WaitGroup
+	wg.Add(1 + consumers) // Add producer and consumer goroutines
+
+	// Producer
+	producedSum := 0
+	completedProducing := int32(0)
+	go func() {
+		defer func() {
+			atomic.AddInt32(&completedProducing, 1)
+			wg.Done()
+		}()
+
+		for i := 0; i < 1000; i++ {
+			for !r.TryPush(i) {
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+			}
+			producedSum += i
+		}
+	}()
+
+	// Consumers, which race each other to commit the same positions while the producer writes to the slots
+	sum := int64(0)
+	for c := 0; c < consumers; c++ {
+		go func() {
+			defer wg.Done()
+
+			for {
+				job, ok := r.TryPop()
+				if !ok {
+					if atomic.LoadInt32(&completedProducing) > 0 {
+						// Drain anything committed after our last empty check
+						for job, ok = r.TryPop(); ok; job, ok = r.TryPop() {
+							atomic.AddInt64(&sum, int64(job))
+						}
+						break
+					}
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					continue
+				}
+
+				atomic.AddInt64(&sum, int64(job))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if int64(producedSum) != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+}
+
+func TestRingAllocs(t *testing.T) {
+	job := 1000
+	testCases := []struct {
+		pushPop        func() func()
+		desc           string
+		expectedAllocs float64
+	}{
+		{
+			desc: "Values that are not pointer shaped are boxed on every push",
+			pushPop: func() func() {
+				r := NewRing[int](6)
+				return func() {
+					r.TryPush(job)
+					r.TryPop()
+				}
+			},
+			expectedAllocs: 1,
+		},
+		{
+			desc: "Pointer shaped values are pushed without allocating",
+			pushPop: func() func() {
+				r := NewRing[*int](6)
+				return func() {
+					r.TryPush(&job)
+					r.TryPop()
+				}
+			},
+			expectedAllocs: 0,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			if allocs := testing.AllocsPerRun(100, tC.pushPop()); tC.expectedAllocs != allocs {
+				subT.Errorf("expected the allocations per push to be %v, got %v", tC.expectedAllocs, allocs)
+			}
+		})
+	}
+}
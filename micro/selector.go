@@ -0,0 +1,124 @@
+package micro
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidWeights is returned by NewWeightedSelector when the weights don't match the queues, or aren't positive.
+var ErrInvalidWeights = errors.New("selector weights must be positive, with one weight per queue")
+
+// Selector pops from whichever of several queues has a job, like a `select` over several channels.
+// Queues are visited in a weighted round-robin order, where each queue may be popped from up to its weight
+// times in a row before the next queue gets its turn, so that no queue can starve the others.
+// A Selector is meant to be used by a single consumer goroutine. Multiple consumers can each
+// use their own Selector over the same queues.
+type Selector struct {
+	signal  chan struct{}
+	queues  []*Q
+	weights []int
+	next    int
+	served  int
+}
+
+// NewSelector creates a Selector over the queues, where every queue has the same weight.
+func NewSelector(queues ...*Q) *Selector {
+	weights := make([]int, len(queues))
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	return &Selector{
+		signal:  make(chan struct{}, 1),
+		queues:  queues,
+		weights: weights,
+	}
+}
+
+// NewWeightedSelector creates a Selector over the queues, where each queue may be popped from up to its weight
+// times in a row. If the weights don't match the queues, or aren't positive, ErrInvalidWeights is returned.
+func NewWeightedSelector(queues []*Q, weights []int) (*Selector, error) {
+	if len(queues) != len(weights) {
+		return nil, ErrInvalidWeights
+	}
+
+	for _, w := range weights {
+		if w <= 0 {
+			return nil, ErrInvalidWeights
+		}
+	}
+
+	return &Selector{
+		signal:  make(chan struct{}, 1),
+		queues:  queues,
+		weights: append([]int(nil), weights...),
+	}, nil
+}
+
+// PopAny will block until a position can be popped from one of the queues, or until the context is done.
+// It returns the index of the queue (in the order that the queues were given to the Selector), the position,
+// and a savepoint, after which PopCommit must be called on that queue as it would be after Pop.
+// While every queue is empty, PopAny parks until Notify is called or a short backoff passes, so producers
+// that call Notify after committing wake it immediately, and producers that don't are still picked up.
+// If the context is done first, `-1, -1, 0, ctx.Err()` will be returned, and if every queue
+// is closed and drained, `-1, -1, 0, ErrClosed` will be returned.
+func (s *Selector) PopAny(ctx context.Context) (int, int, uint32, error) {
+	for attempt := 0; ; attempt++ {
+		closed := 0
+		for range s.queues {
+			i := s.next
+			pos, savepoint, isEmpty := s.queues[i].Pop()
+			if !isEmpty {
+				s.served++
+				if s.served >= s.weights[i] {
+					s.advance()
+				}
+				return i, pos, savepoint, nil
+			}
+
+			if pos == Closed {
+				closed++
+			}
+			s.advance()
+		}
+
+		if closed == len(s.queues) {
+			return -1, -1, 0, ErrClosed
+		}
+
+		if err := s.park(ctx, attempt); err != nil {
+			return -1, -1, 0, err
+		}
+	}
+}
+
+// Notify wakes the Selector if it is parked in PopAny. Producers should call it after committing to any of the queues.
+func (s *Selector) Notify() {
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Selector) advance() {
+	s.served = 0
+	s.next++
+	if s.next == len(s.queues) {
+		s.next = 0
+	}
+}
+
+func (s *Selector) park(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(ctxBackoff.duration(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.signal:
+		return nil
+	case <-timer.C:
+		return nil
+	}
+}
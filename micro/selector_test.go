@@ -0,0 +1,166 @@
+package micro
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSelectorPopAny(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		jobs           []int
+		weights        []int
+		expectedQueues []int
+	}{
+		{
+			desc:           "Queues with jobs are visited in round-robin order",
+			jobs:           []int{10, 10, 10},
+			weights:        []int{1, 1, 1},
+			expectedQueues: []int{0, 1, 2, 0, 1, 2},
+		},
+		{
+			desc:           "Empty queues are skipped",
+			jobs:           []int{10, 0, 10},
+			weights:        []int{1, 1, 1},
+			expectedQueues: []int{0, 2, 0, 2},
+		},
+		{
+			desc:           "Queues are popped from up to their weight in a row",
+			jobs:           []int{10, 10},
+			weights:        []int{2, 1},
+			expectedQueues: []int{0, 0, 1, 0, 0, 1},
+		},
+		{
+			desc:           "Drained queues hand their turn to the others",
+			jobs:           []int{1, 10},
+			weights:        []int{3, 1},
+			expectedQueues: []int{0, 1, 1, 1},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			queues := make([]*Q, len(tC.jobs))
+			for i, jobs := range tC.jobs {
+				queues[i] = NewQ(6)
+				for j := 0; j < jobs; j++ {
+					queues[i].PushCommit()
+				}
+			}
+
+			s, err := NewWeightedSelector(queues, tC.weights)
+			if err != nil {
+				subT.Fatalf("unexpected error creating the selector %v", err)
+			}
+
+			for i, expected := range tC.expectedQueues {
+				queue, _, savepoint, err := s.PopAny(context.Background())
+				if err != nil {
+					subT.Errorf("unexpected error at pop number %d: %v", i, err)
+					return
+				}
+
+				if expected != queue {
+					subT.Errorf("expected pop number %d to be from queue %d but got %d", i, expected, queue)
+				}
+
+				if !queues[queue].PopCommit(savepoint) {
+					subT.Errorf("expected pop commit to pass normally on pop number %d but got commit failed", i)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectorBlocking(t *testing.T) {
+	testCases := []struct {
+		expectedErr error
+		desc        string
+		closeQueues bool
+	}{
+		{
+			desc:        "Empty queues return the deadline error",
+			closeQueues: false,
+			expectedErr: context.DeadlineExceeded,
+		},
+		{
+			desc:        "Closed and drained queues return the closed error",
+			closeQueues: true,
+			expectedErr: ErrClosed,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			queues := []*Q{NewQ(6), NewQ(6)}
+			if tC.closeQueues {
+				for _, q := range queues {
+					q.Close()
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			queue, pos, _, err := NewSelector(queues...).PopAny(ctx)
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+
+			if queue != -1 || pos != -1 {
+				subT.Errorf("expected the queue and position to be -1 on error, got %d and %d", queue, pos)
+			}
+		})
+	}
+}
+
+func TestSelectorNotify(t *testing.T) {
+	queues := []*Q{NewQ(6), NewQ(6)}
+	s := NewSelector(queues...)
+
+	go func() {
+		<-time.After(5 * time.Millisecond)
+		queues[1].PushCommit()
+		s.Notify()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	queue, pos, _, err := s.PopAny(ctx)
+	if err != nil || queue != 1 || pos != 0 {
+		t.Errorf("expected to pop position 0 from queue 1, got position %d from queue %d with error %v", pos, queue, err)
+	}
+}
+
+func TestNewWeightedSelector(t *testing.T) {
+	testCases := []struct {
+		expectedErr error
+		desc        string
+		weights     []int
+	}{
+		{
+			desc:        "Positive weights for every queue are accepted",
+			weights:     []int{1, 2},
+			expectedErr: nil,
+		},
+		{
+			desc:        "Missing weights are rejected",
+			weights:     []int{1},
+			expectedErr: ErrInvalidWeights,
+		},
+		{
+			desc:        "Zero weights are rejected",
+			weights:     []int{1, 0},
+			expectedErr: ErrInvalidWeights,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			_, err := NewWeightedSelector([]*Q{NewQ(6), NewQ(6)}, tC.weights)
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package micro
+
+import (
+	"runtime"
+	"unsafe"
+
+	"github.com/probably-not/q/internal/check"
+)
+
+// Sharded is a set of rings, one per shard, which scales producers across cores without a compare and swap
+// on the push path. Every producer pushes to a shard of its own, so that each shard keeps the single producer
+// guarantee of `micro.Ring`, and consumers pop from their own shard first, and steal from the other shards when it is empty.
+// Every shard is padded like a `micro.PaddedQ`, so producers on different cores don't contend on shared cache lines.
+// Jobs are only ordered within a shard: two jobs pushed to different shards may be popped in any order.
+type Sharded[T any] struct {
+	shards []shard[T]
+}
+
+// shard pads its ring up to a whole number of cache lines, plus one more line, so that in a slice of shards the state
+// word of one shard is always more than a line away from the fields of the next. The pointers of the ring come first,
+// which keeps the padding out of the part of the shard that the garbage collector scans.
+type shard[T any] struct {
+	Ring[T]
+	_ [2*cacheLineSize - unsafe.Sizeof(Ring[struct{}]{})%cacheLineSize]byte
+}
+
+// NewSharded creates a Sharded set of queues with the number of shards, each with a queue of the size factor.
+// If shards is zero or negative, there is one shard per P, as reported by `runtime.GOMAXPROCS(0)`.
+func NewSharded[T any](shards int, queueSizeFactor int) *Sharded[T] {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	s := &Sharded[T]{shards: make([]shard[T], shards)}
+	for i := range s.shards {
+		s.shards[i].init(queueSizeFactor)
+	}
+
+	return s
+}
+
+// NewShardedChecked creates a new Sharded set of queues after validating the size factor.
+// If the factor can't be used with the queues, ErrFactorZero or ErrFactorTooLarge is returned.
+func NewShardedChecked[T any](shards int, queueSizeFactor int) (*Sharded[T], error) {
+	if err := check.FactorU32(queueSizeFactor); err != nil {
+		return nil, err
+	}
+
+	return NewSharded[T](shards, queueSizeFactor), nil
+}
+
+// Shards returns the number of shards.
+func (s *Sharded[T]) Shards() int {
+	return len(s.shards)
+}
+
+// TryPush will attempt to push the value to the shard, which is taken modulo the number of shards,
+// so that any shard, including a negative one, maps to one of the shards.
+// It returns `true` if the value was pushed, or `false` if the shard is full or closed.
+// Only a single producer may push to each shard at a time, which is the guarantee that allows pushes without
+// a compare and swap. Producers that need to spread their values over several shards should use separate shards each.
+func (s *Sharded[T]) TryPush(shard int, v T) bool {
+	return s.shards[s.index(shard)].TryPush(v)
+}
+
+// TryPop will attempt to pop a value from the shard, which is taken modulo the number of shards as in TryPush,
+// and if that shard is empty, will steal from the other shards in order.
+// It returns the value along with `true` if a value was popped, or the zero value of `T`
+// along with `false` if every shard is empty, or closed and drained.
+// Any number of consumers may pop from any shard, and consumers spread over the shards steal from each other
+// only when their own shard runs dry.
+func (s *Sharded[T]) TryPop(shard int) (T, bool) {
+	start := s.index(shard)
+	for i := range s.shards {
+		idx := start + i
+		if idx >= len(s.shards) {
+			idx -= len(s.shards)
+		}
+
+		if v, ok := s.shards[idx].TryPop(); ok {
+			return v, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// Len returns the number of jobs that are committed to all of the shards and not yet popped.
+// Since the shards are not loaded atomically together, it is only a snapshot while jobs are being pushed and popped.
+func (s *Sharded[T]) Len() int {
+	n := 0
+	for i := range s.shards {
+		n += s.shards[i].Len()
+	}
+
+	return n
+}
+
+// Close closes every shard, after which pushes are rejected, and pops drain the jobs that were already committed.
+func (s *Sharded[T]) Close() {
+	for i := range s.shards {
+		s.shards[i].Close()
+	}
+}
+
+// index maps the shard to the index of one of the shards, which is never negative, even for a negative shard.
+func (s *Sharded[T]) index(shard int) int {
+	n := len(s.shards)
+	return (shard%n + n) % n
+}
@@ -0,0 +1,161 @@
+package micro
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestShardedTryPop(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		pushes   []int // Shard of every push, where the value pushed is the index of the push
+		expected []int
+		popShard int
+	}{
+		{
+			desc:     "Values are popped from the consumer's own shard first",
+			pushes:   []int{0, 1, 1},
+			popShard: 1,
+			expected: []int{1, 2, 0},
+		},
+		{
+			desc:     "Values are stolen from the other shards in order once the own shard is empty",
+			pushes:   []int{2, 0, 3},
+			popShard: 1,
+			expected: []int{0, 2, 1},
+		},
+		{
+			desc:     "Shards are taken modulo the number of shards",
+			pushes:   []int{4, 5},
+			popShard: 5,
+			expected: []int{1, 0},
+		},
+		{
+			desc:     "Negative shards are taken modulo the number of shards",
+			pushes:   []int{-1, -2, -5},
+			popShard: -1,
+			expected: []int{0, 2, 1},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			s := NewSharded[int](4, 6)
+			for i, shard := range tC.pushes {
+				if !s.TryPush(shard, i) {
+					subT.Errorf("expected push number %d to be accepted", i)
+				}
+			}
+
+			if s.Len() != len(tC.pushes) {
+				subT.Errorf("expected the length to be %d, got %d", len(tC.pushes), s.Len())
+			}
+
+			for i, expected := range tC.expected {
+				v, ok := s.TryPop(tC.popShard)
+				if !ok || expected != v {
+					subT.Errorf("expected pop number %d to be %d, got %d (ok %t)", i, expected, v, ok)
+				}
+			}
+
+			if _, ok := s.TryPop(tC.popShard); ok {
+				subT.Errorf("expected a pop from empty shards to fail")
+			}
+		})
+	}
+}
+
+func TestShardedDefaultShards(t *testing.T) {
+	if s := NewSharded[int](0, 6); s.Shards() < 1 {
+		t.Errorf("expected at least one shard by default, got %d", s.Shards())
+	}
+}
+
+func TestShardedClose(t *testing.T) {
+	s := NewSharded[int](2, 6)
+	s.TryPush(0, 1)
+	s.Close()
+
+	if s.TryPush(1, 2) {
+		t.Errorf("expected a push to a closed shard to be rejected")
+	}
+
+	if v, ok := s.TryPop(1); !ok || v != 1 {
+		t.Errorf("expected the committed value to be drained after closing, got %d (ok %t)", v, ok)
+	}
+}
+
+func TestShardedConcurrent(t *testing.T) {
+	const producers = 4
+	s := NewSharded[int64](producers, 4)
+	completedProducing := int32(0)
+
+	var producerWg sync.WaitGroup
+	producedSum := int64(0)
+	for p := 0; p < producers; p++ {
+		producerWg.Add(1)
+
+		// Every producer has a shard of its own
+		go func(shard int) {
+			defer producerWg.Done()
+
+			for i := int64(0); i < 1000; i++ {
+				if !s.TryPush(shard, i) {
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					i--
+					continue
+				}
+				atomic.AddInt64(&producedSum, i)
+			}
+		}(p)
+	}
+
+	var consumerWg sync.WaitGroup
+	sum := int64(0)
+	for c := 0; c < 6; c++ {
+		consumerWg.Add(1)
+
+		go func(shard int) {
+			defer consumerWg.Done()
+
+			for {
+				v, ok := s.TryPop(shard)
+				if !ok {
+					if atomic.LoadInt32(&completedProducing) == 1 && s.Len() == 0 {
+						break
+					}
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					continue
+				}
+
+				atomic.AddInt64(&sum, v)
+			}
+		}(c)
+	}
+
+	producerWg.Wait()
+	atomic.StoreInt32(&completedProducing, 1)
+	consumerWg.Wait()
+
+	if producedSum != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+}
+
+func TestShardedLayout(t *testing.T) {
+	s := NewSharded[int](3, 6)
+
+	for i := 1; i < len(s.shards); i++ {
+		prev := uintptr(unsafe.Pointer(&s.shards[i-1].q))
+		next := uintptr(unsafe.Pointer(&s.shards[i].slots))
+		if next-prev < cacheLineSize {
+			t.Errorf("expected the state word of a shard to be at least %d bytes from the next shard, got %d", cacheLineSize, next-prev)
+		}
+	}
+
+	if size := unsafe.Sizeof(shard[int]{}); size%cacheLineSize != 0 {
+		t.Errorf("expected the shard to fill whole cache lines, got %d bytes", size)
+	}
+}
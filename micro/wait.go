@@ -0,0 +1,176 @@
+package micro
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// WaitStrategy decides how PushWait and PopWait wait between attempts on a full or empty queue.
+// Since the queue itself holds no waiters, the opposite side of the queue is responsible for calling Notify
+// after it commits, so that strategies which park can be woken up. Strategies that don't park ignore Notify.
+type WaitStrategy interface {
+	// Wait is called after every failed attempt, with the number of attempts that have failed before it.
+	Wait(attempt int)
+	// Notify is called after a commit on the opposite side of the queue, to wake a caller that is parked in Wait.
+	Notify()
+}
+
+// Spin is a WaitStrategy which retries immediately, busy looping until the operation succeeds.
+// It gives the lowest latency, at the cost of burning a full core while waiting.
+type Spin struct{}
+
+func (Spin) Wait(int) {}
+func (Spin) Notify()  {}
+
+// SpinYield is a WaitStrategy which retries immediately for the first Spins attempts,
+// and then yields the processor with `runtime.Gosched` between every following attempt.
+type SpinYield struct {
+	Spins int
+}
+
+func (s SpinYield) Wait(attempt int) {
+	if attempt < s.Spins {
+		return
+	}
+
+	runtime.Gosched()
+}
+
+func (SpinYield) Notify() {}
+
+// Backoff is a WaitStrategy which sleeps between attempts, starting at Min and doubling on every attempt
+// until it reaches Max. A Min below one nanosecond is treated as one nanosecond, so that Backoff always sleeps,
+// and a Max below Min is treated as Min.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (b Backoff) Wait(attempt int) {
+	time.Sleep(b.duration(attempt))
+}
+
+// WaitCtx sleeps as Wait does, returning early with the context's error if the context is done first.
+// It lets callers that block on a context back off in the same way as the context aware operations of `micro.Q`.
+func (b Backoff) WaitCtx(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(b.duration(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (b Backoff) duration(attempt int) time.Duration {
+	lo, hi := b.Min, b.Max
+	if lo < time.Nanosecond {
+		lo = time.Nanosecond
+	}
+
+	if hi < lo {
+		hi = lo
+	}
+
+	// Only shift while the result stays within Max, so that a large Min can never overflow
+	if lo <= hi>>attempt {
+		return lo << attempt
+	}
+
+	return hi
+}
+
+func (Backoff) Notify() {}
+
+// Park is a WaitStrategy which parks the waiting goroutine until the opposite side of the queue calls Notify.
+// A notification that arrives before the waiter parks is kept, so it can't be lost between a failed
+// attempt and the call to Wait. Neither the queue nor PushWait and PopWait call Notify, so every commit
+// on the opposite side must be followed by a call to Notify, otherwise a parked waiter may never be woken.
+type Park struct {
+	signal chan struct{}
+}
+
+func NewPark() *Park {
+	return &Park{
+		signal: make(chan struct{}, 1),
+	}
+}
+
+func (p *Park) Wait(int) {
+	<-p.signal
+}
+
+func (p *Park) Notify() {
+	select {
+	case p.signal <- struct{}{}:
+	default:
+	}
+}
+
+// PushWait will block until a position can be pushed to in the queue, calling the WaitStrategy between attempts.
+// It returns the position, after which PushCommit must be called as it would be after Push.
+// PushWait doesn't notify the consumers' WaitStrategy, so the caller must call its Notify after PushCommit.
+// If the queue is closed, `Closed` will be returned instead of blocking.
+func (q *Q) PushWait(ws WaitStrategy) int {
+	for attempt := 0; ; attempt++ {
+		pos, isFull := q.Push()
+		if !isFull || pos == Closed {
+			return pos
+		}
+
+		ws.Wait(attempt)
+	}
+}
+
+// PopWait will block until a position can be popped from the queue, calling the WaitStrategy between attempts.
+// It returns the position and a savepoint, after which PopCommit must be called as it would be after Pop.
+// If the commit fails, PopWait can be called again to wait for the next position.
+// PopWait doesn't notify the producer's WaitStrategy, so the caller must call its Notify after a successful PopCommit.
+// If the queue is closed and drained, `Closed, 0` will be returned instead of blocking.
+// A waiter that is parked when the queue is closed is only woken by a Notify, so Close must be followed by one,
+// as described on Close.
+func (q *Q) PopWait(ws WaitStrategy) (int, uint32) {
+	for attempt := 0; ; attempt++ {
+		pos, savepoint, isEmpty := q.Pop()
+		if !isEmpty || pos == Closed {
+			return pos, savepoint
+		}
+
+		ws.Wait(attempt)
+	}
+}
+
+// PushWait will block until the value can be pushed to the ring, calling the WaitStrategy between attempts.
+// It returns `true` once the value is pushed, or `false` if the ring is closed.
+// As with `Q.PushWait`, the caller must call Notify on the consumers' WaitStrategy afterwards.
+func (r *Ring[T]) PushWait(ws WaitStrategy, v T) bool {
+	pos := r.Q.PushWait(ws)
+	if pos == Closed {
+		return false
+	}
+
+	r.store(pos, v)
+	r.PushCommit()
+	return true
+}
+
+// PopWait will block until a value can be popped from the ring, calling the WaitStrategy between attempts.
+// It returns the value along with `true`, or the zero value of `T` along with `false` if the ring is closed and drained.
+// As with `Q.PopWait`, the caller must call Notify on the producer's WaitStrategy afterwards.
+func (r *Ring[T]) PopWait(ws WaitStrategy) (T, bool) {
+	for {
+		pos, savepoint := r.Q.PopWait(ws)
+		if pos == Closed {
+			var zero T
+			return zero, false
+		}
+
+		v := r.load(pos)
+		if r.PopCommit(savepoint) {
+			return v, true
+		}
+	}
+}
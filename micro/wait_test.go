@@ -0,0 +1,249 @@
+package micro
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPushWaitPopWait(t *testing.T) {
+	testCases := []struct {
+		notFull  WaitStrategy
+		notEmpty WaitStrategy
+		desc     string
+	}{
+		{
+			desc:     "Spin strategy",
+			notFull:  Spin{},
+			notEmpty: Spin{},
+		},
+		{
+			desc:     "Spin then yield strategy",
+			notFull:  SpinYield{Spins: 100},
+			notEmpty: SpinYield{Spins: 100},
+		},
+		{
+			desc:     "Exponential backoff strategy",
+			notFull:  Backoff{Min: 1 * time.Microsecond, Max: 1 * time.Millisecond},
+			notEmpty: Backoff{Min: 1 * time.Microsecond, Max: 1 * time.Millisecond},
+		},
+		{
+			desc:     "Park strategy",
+			notFull:  NewPark(),
+			notEmpty: NewPark(),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			const queueSizeFactor = 4 // Small queue so that both sides have to wait
+			const availableSlots = 1 << queueSizeFactor
+			q := NewQ(queueSizeFactor)
+			jobs := [availableSlots]int{}
+
+			var wg sync.WaitGroup
+			wg.Add(2) // Add producer and consumer goroutines
+
+			// Producer
+			producedSum := 0
+			go func() {
+				defer wg.Done()
+
+				for i := 0; i < 1000; i++ {
+					slot := q.PushWait(tC.notFull)
+					jobs[slot] = i
+					producedSum += i
+					q.PushCommit()
+					tC.notEmpty.Notify()
+				}
+			}()
+
+			// Consumer
+			sum := 0
+			go func() {
+				defer wg.Done()
+
+				for consumed := 0; consumed < 1000; {
+					slot, savepoint := q.PopWait(tC.notEmpty)
+					job := jobs[slot]
+					if !q.PopCommit(savepoint) {
+						continue // Commit failed so we can't run the job
+					}
+					tC.notFull.Notify()
+					sum += job
+					consumed++
+				}
+			}()
+
+			wg.Wait()
+
+			if producedSum != sum {
+				subT.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+			}
+		})
+	}
+}
+
+func TestParkKeepsEarlyNotify(t *testing.T) {
+	p := NewPark()
+	p.Notify()
+	p.Notify() // Extra notifications are dropped rather than blocking the notifier
+
+	done := make(chan struct{})
+	go func() {
+		p.Wait(0)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Errorf("expected a notify before the wait to wake the waiter")
+	}
+}
+
+func TestBackoffDuration(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		backoff  Backoff
+		attempt  int
+		expected time.Duration
+	}{
+		{
+			desc:     "First attempt waits the minimum",
+			backoff:  Backoff{Min: 1 * time.Microsecond, Max: 1 * time.Millisecond},
+			attempt:  0,
+			expected: 1 * time.Microsecond,
+		},
+		{
+			desc:     "Doubles on every attempt",
+			backoff:  Backoff{Min: 1 * time.Microsecond, Max: 1 * time.Millisecond},
+			attempt:  3,
+			expected: 8 * time.Microsecond,
+		},
+		{
+			desc:     "Caps at the maximum",
+			backoff:  Backoff{Min: 1 * time.Microsecond, Max: 1 * time.Millisecond},
+			attempt:  20,
+			expected: 1 * time.Millisecond,
+		},
+		{
+			desc:     "Zero minimum still sleeps",
+			backoff:  Backoff{Min: 0, Max: 1 * time.Millisecond},
+			attempt:  0,
+			expected: 1 * time.Nanosecond,
+		},
+		{
+			desc:     "Maximum below the minimum waits the minimum",
+			backoff:  Backoff{Min: 1 * time.Millisecond, Max: 0},
+			attempt:  5,
+			expected: 1 * time.Millisecond,
+		},
+		{
+			desc:     "Large minimum doesn't overflow",
+			backoff:  Backoff{Min: 1 << 40, Max: 1 << 62},
+			attempt:  31,
+			expected: 1 << 62,
+		},
+		{
+			desc:     "Attempts past the width of the duration cap at the maximum",
+			backoff:  Backoff{Min: 1 * time.Microsecond, Max: 1 * time.Millisecond},
+			attempt:  100,
+			expected: 1 * time.Millisecond,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			if d := tC.backoff.duration(tC.attempt); d != tC.expected {
+				subT.Errorf("expected the duration to be %v, got %v", tC.expected, d)
+			}
+		})
+	}
+}
+
+func TestParkWakesOnClose(t *testing.T) {
+	const consumers = 4
+	q := NewQ(4)
+	notEmpty := NewPark()
+
+	var wg sync.WaitGroup
+	wg.Add(consumers)
+	for c := 0; c < consumers; c++ {
+		go func() {
+			defer wg.Done()
+
+			pos, _ := q.PopWait(notEmpty)
+			if pos != Closed {
+				t.Errorf("expected the position to be %d, got %d", Closed, pos)
+			}
+			notEmpty.Notify() // Pass the notification along to the next parked consumer
+		}()
+	}
+
+	<-time.After(10 * time.Millisecond) // Allow the consumers to park
+	q.Close()
+	notEmpty.Notify()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Errorf("expected every parked consumer to wake after Close")
+	}
+}
+
+func TestRingPushWaitPopWait(t *testing.T) {
+	const queueSizeFactor = 4 // Small ring so that both sides have to wait
+	r := NewRing[int](queueSizeFactor)
+	notFull, notEmpty := NewPark(), NewPark()
+
+	var wg sync.WaitGroup
+	wg.Add(2) // Add producer and consumer goroutines
+
+	// Producer
+	producedSum := 0
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 1000; i++ {
+			if !r.PushWait(notFull, i) {
+				t.Errorf("expected the push of %d to succeed", i)
+				return
+			}
+			producedSum += i
+			notEmpty.Notify()
+		}
+
+		r.Close()
+		notEmpty.Notify()
+	}()
+
+	// Consumer
+	sum := 0
+	go func() {
+		defer wg.Done()
+
+		for {
+			job, ok := r.PopWait(notEmpty)
+			if !ok {
+				return
+			}
+			notFull.Notify()
+			sum += job
+		}
+	}()
+
+	wg.Wait()
+
+	if producedSum != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+
+	if r.PushWait(notFull, 1) {
+		t.Errorf("expected the push to a closed ring to fail")
+	}
+}
@@ -0,0 +1,16 @@
+// Package milli contains the fourth version of the implementation of this queue.
+// It enables a multiple producer/multiple consumer architecture to work on the same slice of jobs,
+// with the indices of jobs pushed to the queue and jobs popped from the queue fully managed by the `milli.Q`.
+// The slice of jobs itself is managed by an outside source, however access to this slice of jobs should be fully
+// managed by the `milli.Q` type.
+// The consumer side is identical to `nano.Q`: Pop returns a savepoint, and PopCommit uses it in a CAS to ensure that
+// only a single consumer receives each job.
+// The producer side can't simply mirror this, since a producer must write its job before the job becomes visible,
+// and two producers writing to the same position before one of them fails its commit would corrupt the job.
+// Instead, producers reserve positions from a second counter held next to the packed head/tail word. Push claims a
+// position with a CAS on the reservation counter, and PushCommit publishes it by moving the head with a CAS on the
+// packed word. Positions are published in the order they were reserved, so PushCommit fails until every earlier
+// reservation has been committed, and must be retried until it succeeds.
+// The caveat from `nano` still applies to the consumers: a consumer that fails its commit may have read a position
+// that a producer is concurrently writing to, so access to the slice of jobs must be threadsafe.
+package milli
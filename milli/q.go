@@ -0,0 +1,166 @@
+package milli
+
+import (
+	"sync/atomic"
+
+	"github.com/probably-not/q/internal/check"
+	"github.com/probably-not/q/internal/consts"
+)
+
+var (
+	// ErrFactorTooLarge is returned when the size factor does not fit in the halves of the queue state.
+	ErrFactorTooLarge = check.ErrFactorTooLarge
+	// ErrFactorZero is returned when the size factor is zero or negative.
+	ErrFactorZero = check.ErrFactorZero
+)
+
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
+
+type Q struct {
+	noCopy
+	q       uint32
+	reserve uint32
+}
+
+func NewQ() *Q {
+	return &Q{
+		q:       0,
+		reserve: 0,
+	}
+}
+
+// NewQChecked creates a new queue after validating the size factor that will be passed to its operations.
+// If the factor can't be used with the queue, ErrFactorZero or ErrFactorTooLarge is returned.
+func NewQChecked(factor int) (*Q, error) {
+	if err := check.FactorU32(factor); err != nil {
+		return nil, err
+	}
+
+	return NewQ(), nil
+}
+
+// Pop will calculate the position that can currently be popped from the queue.
+// It returns the position, a save point (to allow ensuring the commit),
+// along with a boolean indicating if the queue is empty or not.
+// If the queue is empty, `-1, 0, true` will be returned.
+// If the queue is not empty, `pos, savepoint, false` will be returned.
+// After receiving the position and savepoint, PopCommit must be called in
+// order to ensure that the job is truly the caller's job, and it has not been
+// committed by another consumer.
+func (q *Q) Pop(factor int) (int, uint32, bool) {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32(&q.q)
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	if head == tail {
+		return -1, 0, true
+	}
+
+	return int(tail), acquired, false
+}
+
+// PopCommit will commit the previously executed Pop operation to the queue.
+// This moves the index of the queue to the next pop-able index.
+// It requires a savepoint that was returned by the Pop operation, which will
+// be used to ensure that the operation is in fact atomic.
+// If the commit succeeds, `true` is returned to indicate that the caller does in fact
+// have the job that it received in the Pop operation.
+func (q *Q) PopCommit(savepoint uint32) bool {
+	return atomic.CompareAndSwapUint32(&q.q, savepoint, uint32(savepoint+consts.CommitPopU32))
+}
+
+// Push will reserve the position that can currently be pushed to in the queue.
+// It returns the position, a save point (to allow publishing the position in order),
+// along with a boolean indicating if the queue is full or not.
+// If the queue is full, `-1, 0, true` will be returned.
+// If the queue is not full, `pos, savepoint, false` will be returned.
+// Unlike the other queues, the position is owned by the caller as soon as Push returns,
+// and the caller must eventually call PushCommit with the savepoint, since no later
+// position can be published until this one is.
+func (q *Q) Push(factor int) (int, uint32, bool) {
+	check.AssertFactorU32(factor)
+	mask := (uint32(1) << factor) - 1
+
+	for {
+		reserved := atomic.LoadUint32(&q.reserve)
+		acquired := atomic.LoadUint32(&q.q)
+		tail := acquired >> 16 & mask
+		next := (reserved + uint32(1)) & mask
+
+		if next == tail {
+			return -1, 0, true
+		}
+
+		if atomic.CompareAndSwapUint32(&q.reserve, reserved, reserved+1) {
+			return int(reserved & mask), reserved, false
+		}
+	}
+}
+
+// PushCommit will commit the previously executed Push operation to the queue.
+// This moves the index of the queue to the next push-able index, making the job visible to consumers.
+// It requires a savepoint that was returned by the Push operation, which will
+// be used to ensure that positions are published in the order they were reserved.
+// If the commit fails, `false` is returned, and the caller must retry the commit until it succeeds.
+// A commit fails while an earlier reservation is still being written, or when a consumer
+// moved the queue concurrently.
+func (q *Q) PushCommit(savepoint uint32) bool {
+	acquired := atomic.LoadUint32(&q.q)
+	if (acquired^savepoint)&consts.PushHeadMaskU32 != 0 {
+		return false
+	}
+
+	next := acquired + 1
+	if next&consts.PushOverflowCheckU32 != 0 {
+		next += consts.PushOverflowProtectionU32
+	}
+
+	return atomic.CompareAndSwapUint32(&q.q, acquired, next)
+}
+
+// Len returns the number of jobs that are committed to the queue and not yet popped.
+// It is decoded from a single atomic load, and unlike a Push probe it has no side effects.
+func (q *Q) Len(factor int) int {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32(&q.q)
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	return int((head - tail) & mask)
+}
+
+// Cap returns the number of jobs that the queue can hold, which is one less than its number of positions,
+// since a full queue always keeps one position free to tell it apart from an empty queue.
+func (q *Q) Cap(factor int) int {
+	check.AssertFactorU32(factor)
+	return (1 << factor) - 1
+}
+
+// IsEmpty reports whether the queue is empty, from a single atomic load and without side effects.
+func (q *Q) IsEmpty(factor int) bool {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32(&q.q)
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	return head == tail
+}
+
+// IsFull reports whether the queue is full, without side effects.
+// Positions that are reserved by a Push but not yet committed count towards a full queue.
+func (q *Q) IsFull(factor int) bool {
+	check.AssertFactorU32(factor)
+	reserved := atomic.LoadUint32(&q.reserve)
+	acquired := atomic.LoadUint32(&q.q)
+	mask := (uint32(1) << factor) - 1
+	tail := acquired >> 16 & mask
+
+	return (reserved+uint32(1))&mask == tail
+}
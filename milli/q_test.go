@@ -0,0 +1,425 @@
+package milli
+
+import (
+	"errors"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPop(t *testing.T) {
+	testCases := []struct {
+		queue               *Q
+		desc                string
+		expectedAllowedPops int
+		expectedIdx         int
+		queueSizeFactor     int
+		expectedIsEmpty     bool
+	}{
+		{
+			desc:            "Zero value of queue is empty",
+			queue:           NewQ(),
+			expectedIdx:     -1,
+			expectedIsEmpty: true,
+			queueSizeFactor: 6,
+		},
+		{
+			desc: "Pops are allowed as many times as there are jobs in the queue, and when completed return empty",
+			queue: func() *Q {
+				q := NewQ()
+				for i := 0; i < 10; i++ {
+					_, savepoint, _ := q.Push(6)
+					q.PushCommit(savepoint) // 10 pushes
+				}
+				return q
+			}(),
+			expectedAllowedPops: 10,
+			expectedIdx:         -1,
+			expectedIsEmpty:     true,
+			queueSizeFactor:     6,
+		},
+		{
+			desc: "Pops are allowed as many times as there are jobs in the queue, and when not completed return the next index",
+			queue: func() *Q {
+				q := NewQ()
+				for i := 0; i < 10; i++ {
+					_, savepoint, _ := q.Push(6)
+					q.PushCommit(savepoint) // 10 pushes
+				}
+				return q
+			}(),
+			expectedAllowedPops: 8,
+			expectedIdx:         8,
+			expectedIsEmpty:     false,
+			queueSizeFactor:     6,
+		},
+		{
+			desc: "Reserved but uncommitted pushes are not pop-able",
+			queue: func() *Q {
+				q := NewQ()
+				for i := 0; i < 10; i++ {
+					q.Push(6) // 10 reservations without commits
+				}
+				return q
+			}(),
+			expectedIdx:     -1,
+			expectedIsEmpty: true,
+			queueSizeFactor: 6,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			for i := 0; i < tC.expectedAllowedPops; i++ {
+				idx, savepoint, isEmpty := tC.queue.Pop(tC.queueSizeFactor)
+				if idx == -1 || isEmpty {
+					subT.Errorf("unexpected empty queue during allowed pops at pop number %d", i)
+					return
+				}
+
+				if i != idx {
+					subT.Errorf("expected popped job to be %d but got %d", i, idx)
+				}
+
+				if !tC.queue.PopCommit(savepoint) {
+					subT.Errorf("expected pop commit to pass normally on job %d but got commit failed", i)
+				}
+			}
+
+			idx, _, isEmpty := tC.queue.Pop(tC.queueSizeFactor)
+			if tC.expectedIdx != idx {
+				subT.Errorf("expected the returned index to be %d, got %d", tC.expectedIdx, idx)
+			}
+
+			if tC.expectedIsEmpty != isEmpty {
+				subT.Errorf("expected isEmpty to be %t, got %t", tC.expectedIsEmpty, isEmpty)
+			}
+		})
+	}
+}
+
+func TestPush(t *testing.T) {
+	randomAmountOfJobs := uint32(rand.Intn(62))
+
+	testCases := []struct {
+		queue              *Q
+		desc               string
+		expectedIdx        int
+		nextExpectedIdx    int
+		queueSizeFactor    int
+		expectedIsFull     bool
+		nextExpectedIsFull bool
+		expectedCommitted  bool
+	}{
+		{
+			desc:               "Zero value of queue allows pushing",
+			queue:              NewQ(),
+			expectedIsFull:     false,
+			nextExpectedIsFull: false,
+			expectedIdx:        0,
+			nextExpectedIdx:    1,
+			queueSizeFactor:    6,
+			expectedCommitted:  true,
+		},
+		{
+			desc:               "Queue with random amount of jobs less than size factor allows pushing",
+			queue:              &Q{q: randomAmountOfJobs, reserve: randomAmountOfJobs},
+			expectedIsFull:     false,
+			nextExpectedIsFull: false,
+			expectedIdx:        int(randomAmountOfJobs),
+			nextExpectedIdx:    int(randomAmountOfJobs) + 1,
+			queueSizeFactor:    6,
+			expectedCommitted:  true,
+		},
+		{
+			desc:               "Queue at the size factor is marked as full and cannot be pushed to",
+			queue:              &Q{q: 63, reserve: 63},
+			expectedIsFull:     true,
+			nextExpectedIsFull: true,
+			expectedIdx:        -1,
+			nextExpectedIdx:    -1,
+			queueSizeFactor:    6,
+		},
+		{
+			desc:               "Reservations that are not committed count towards a full queue",
+			queue:              &Q{q: 0, reserve: 62},
+			expectedIsFull:     false,
+			nextExpectedIsFull: true,
+			expectedIdx:        62,
+			nextExpectedIdx:    -1,
+			queueSizeFactor:    6,
+		},
+		{
+			desc:               "Overflow is protected against",
+			queue:              &Q{q: 0x7ff0<<16 | 0x7fff, reserve: 0x7fff},
+			expectedIsFull:     false,
+			nextExpectedIsFull: false,
+			expectedIdx:        63,
+			nextExpectedIdx:    0,
+			queueSizeFactor:    6,
+			expectedCommitted:  true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			idx, savepoint, isFull := tC.queue.Push(tC.queueSizeFactor)
+			if tC.expectedIdx != idx {
+				subT.Errorf("expected the returned index to be %d, got %d", tC.expectedIdx, idx)
+			}
+
+			if tC.expectedIsFull != isFull {
+				subT.Errorf("expected isFull to be %t, got %t", tC.expectedIsFull, isFull)
+			}
+
+			if !isFull {
+				committed := tC.queue.PushCommit(savepoint)
+				if tC.expectedCommitted != committed {
+					subT.Errorf("expected committed to be %t, got %t", tC.expectedCommitted, committed)
+				}
+			}
+
+			idx, _, isFull = tC.queue.Push(tC.queueSizeFactor)
+			if tC.nextExpectedIdx != idx {
+				subT.Errorf("expected the next returned index to be %d, got %d", tC.nextExpectedIdx, idx)
+			}
+
+			if tC.nextExpectedIsFull != isFull {
+				subT.Errorf("expected next isFull to be %t, got %t", tC.nextExpectedIsFull, isFull)
+			}
+		})
+	}
+}
+
+func TestPushCommitOrdering(t *testing.T) {
+	q := NewQ()
+	const queueSizeFactor = 6
+
+	_, first, _ := q.Push(queueSizeFactor)
+	_, second, _ := q.Push(queueSizeFactor)
+
+	if q.PushCommit(second) {
+		t.Errorf("expected the second reservation to fail its commit before the first is committed")
+	}
+
+	if _, _, isEmpty := q.Pop(queueSizeFactor); !isEmpty {
+		t.Errorf("expected the queue to be empty while no reservation is committed")
+	}
+
+	if !q.PushCommit(first) {
+		t.Errorf("expected the first reservation to commit")
+	}
+
+	if !q.PushCommit(second) {
+		t.Errorf("expected the second reservation to commit after the first")
+	}
+
+	for i := 0; i < 2; i++ {
+		idx, savepoint, isEmpty := q.Pop(queueSizeFactor)
+		if isEmpty || idx != i {
+			t.Errorf("expected popped job to be %d but got %d", i, idx)
+		}
+		q.PopCommit(savepoint)
+	}
+}
+
+func TestConcurrentWorkMultipleProducersMultipleConsumers(t *testing.T) {
+	q := NewQ()
+	const queueSizeFactor = 6
+	const availableSlots = 1 << queueSizeFactor
+	const producers = 10
+	const jobsPerProducer = 1000
+	// Consumers that fail their commit may read a slot that a producer is writing to, so all access is atomic.
+	jobs := [availableSlots]int64{}
+
+	var wg sync.WaitGroup
+
+	// Producers
+	producedSum := int64(0)
+	completedProducing := int32(0)
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				atomic.AddInt32(&completedProducing, 1)
+				wg.Done()
+			}()
+
+			for i := int64(0); i < jobsPerProducer; i++ {
+				slot, savepoint, isFull := q.Push(queueSizeFactor)
+				if isFull {
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					i--
+					continue
+				}
+
+				atomic.StoreInt64(&jobs[slot], i)
+				atomic.AddInt64(&producedSum, i)
+				for !q.PushCommit(savepoint) {
+					runtime.Gosched() // An earlier reservation is still being written, so retry until it's our turn
+				}
+			}
+		}()
+	}
+
+	// Consumers
+	sum := int64(0)
+	consumed := int64(0)
+	for c := 0; c < producers; c++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				slot, savepoint, isEmpty := q.Pop(queueSizeFactor)
+				if isEmpty {
+					if atomic.LoadInt32(&completedProducing) == producers {
+						if _, _, isEmpty = q.Pop(queueSizeFactor); isEmpty {
+							break
+						}
+						continue
+					}
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					continue
+				}
+
+				job := atomic.LoadInt64(&jobs[slot])
+				if !q.PopCommit(savepoint) {
+					continue // Commit failed so we can't run the job
+				}
+
+				atomic.AddInt64(&sum, job)
+				atomic.AddInt64(&consumed, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if consumed != producers*jobsPerProducer {
+		t.Errorf("expected %d jobs to be consumed but got %d", producers*jobsPerProducer, consumed)
+	}
+
+	if producedSum != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+}
+
+func TestNewQChecked(t *testing.T) {
+	testCases := []struct {
+		expectedErr     error
+		desc            string
+		queueSizeFactor int
+	}{
+		{
+			desc:            "Factor within the supported range is accepted",
+			queueSizeFactor: 6,
+			expectedErr:     nil,
+		},
+		{
+			desc:            "Largest supported factor is accepted",
+			queueSizeFactor: 15,
+			expectedErr:     nil,
+		},
+		{
+			desc:            "Factor larger than the 16 bit halves allow is rejected",
+			queueSizeFactor: 16,
+			expectedErr:     ErrFactorTooLarge,
+		},
+		{
+			desc:            "Zero factor is rejected",
+			queueSizeFactor: 0,
+			expectedErr:     ErrFactorZero,
+		},
+		{
+			desc:            "Negative factor is rejected",
+			queueSizeFactor: -1,
+			expectedErr:     ErrFactorZero,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			_, err := NewQChecked(tC.queueSizeFactor)
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	const queueSizeFactor = 6
+
+	testCases := []struct {
+		queue           *Q
+		desc            string
+		expectedLen     int
+		expectedIsEmpty bool
+		expectedIsFull  bool
+	}{
+		{
+			desc:            "Zero value of queue is empty",
+			queue:           &Q{q: 0, reserve: 0},
+			expectedLen:     0,
+			expectedIsEmpty: true,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue with jobs reports their count",
+			queue:           &Q{q: 10, reserve: 10},
+			expectedLen:     10,
+			expectedIsEmpty: false,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue at the size factor is full",
+			queue:           &Q{q: 63, reserve: 63},
+			expectedLen:     63,
+			expectedIsEmpty: false,
+			expectedIsFull:  true,
+		},
+		{
+			desc:            "Queue that has wrapped around reports the jobs across the boundary",
+			queue:           &Q{q: 60<<16 | 4, reserve: 4},
+			expectedLen:     8,
+			expectedIsEmpty: false,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue that has overflowed is empty",
+			queue:           &Q{q: 4294967295, reserve: 0xffff},
+			expectedLen:     0,
+			expectedIsEmpty: true,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Reserved but uncommitted positions count towards a full queue",
+			queue:           &Q{q: 0, reserve: 63},
+			expectedLen:     0,
+			expectedIsEmpty: true,
+			expectedIsFull:  true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			if l := tC.queue.Len(queueSizeFactor); tC.expectedLen != l {
+				subT.Errorf("expected the length to be %d, got %d", tC.expectedLen, l)
+			}
+
+			if c := tC.queue.Cap(queueSizeFactor); c != 63 {
+				subT.Errorf("expected the capacity to be %d, got %d", 63, c)
+			}
+
+			if isEmpty := tC.queue.IsEmpty(queueSizeFactor); tC.expectedIsEmpty != isEmpty {
+				subT.Errorf("expected isEmpty to be %t, got %t", tC.expectedIsEmpty, isEmpty)
+			}
+
+			if isFull := tC.queue.IsFull(queueSizeFactor); tC.expectedIsFull != isFull {
+				subT.Errorf("expected isFull to be %t, got %t", tC.expectedIsFull, isFull)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package nano
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPushN(t *testing.T) {
+	const queueSizeFactor = 6
+
+	testCases := []struct {
+		desc              string
+		n                 int
+		expectedStart     int
+		expectedCount     int
+		nextExpectedStart int
+		nextExpectedCount int
+		queue             Q
+	}{
+		{
+			desc:              "Zero value of queue allows pushing a full batch",
+			queue:             Q(0),
+			n:                 10,
+			expectedStart:     0,
+			expectedCount:     10,
+			nextExpectedStart: 10,
+			nextExpectedCount: 10,
+		},
+		{
+			desc:              "Batch is cut short at the mask boundary",
+			queue:             Q(60<<16 | 60),
+			n:                 10,
+			expectedStart:     60,
+			expectedCount:     4,
+			nextExpectedStart: 0,
+			nextExpectedCount: 10,
+		},
+		{
+			desc:              "Batch is cut short by the free positions",
+			queue:             Q(10),
+			n:                 100,
+			expectedStart:     10,
+			expectedCount:     53,
+			nextExpectedStart: -1,
+			nextExpectedCount: 0,
+		},
+		{
+			desc:              "Full queue returns an empty range",
+			queue:             Q(63),
+			n:                 10,
+			expectedStart:     -1,
+			expectedCount:     0,
+			nextExpectedStart: -1,
+			nextExpectedCount: 0,
+		},
+		{
+			desc:              "Overflow is protected against",
+			queue:             Q(4294967295),
+			n:                 10,
+			expectedStart:     63,
+			expectedCount:     1,
+			nextExpectedStart: 0,
+			nextExpectedCount: 10,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			start, count := tC.queue.PushN(queueSizeFactor, tC.n)
+			if tC.expectedStart != start {
+				subT.Errorf("expected the returned start to be %d, got %d", tC.expectedStart, start)
+			}
+
+			if tC.expectedCount != count {
+				subT.Errorf("expected the returned count to be %d, got %d", tC.expectedCount, count)
+			}
+
+			tC.queue.PushCommitN(count)
+
+			start, count = tC.queue.PushN(queueSizeFactor, tC.n)
+			if tC.nextExpectedStart != start {
+				subT.Errorf("expected the next returned start to be %d, got %d", tC.nextExpectedStart, start)
+			}
+
+			if tC.nextExpectedCount != count {
+				subT.Errorf("expected the next returned count to be %d, got %d", tC.nextExpectedCount, count)
+			}
+		})
+	}
+}
+
+func TestPopN(t *testing.T) {
+	const queueSizeFactor = 6
+
+	testCases := []struct {
+		desc              string
+		n                 int
+		expectedStart     int
+		expectedCount     int
+		nextExpectedStart int
+		nextExpectedCount int
+		queue             Q
+	}{
+		{
+			desc:              "Zero value of queue is empty",
+			queue:             Q(0),
+			n:                 10,
+			expectedStart:     -1,
+			expectedCount:     0,
+			nextExpectedStart: -1,
+			nextExpectedCount: 0,
+		},
+		{
+			desc:              "Batch is limited by the requested count",
+			queue:             Q(10),
+			n:                 4,
+			expectedStart:     0,
+			expectedCount:     4,
+			nextExpectedStart: 4,
+			nextExpectedCount: 4,
+		},
+		{
+			desc:              "Batch is cut short at the mask boundary",
+			queue:             Q(60<<16 | 4),
+			n:                 100,
+			expectedStart:     60,
+			expectedCount:     4,
+			nextExpectedStart: 0,
+			nextExpectedCount: 4,
+		},
+		{
+			desc:              "Batch takes every job in the queue",
+			queue:             Q(10),
+			n:                 100,
+			expectedStart:     0,
+			expectedCount:     10,
+			nextExpectedStart: -1,
+			nextExpectedCount: 0,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			start, count, savepoint := tC.queue.PopN(queueSizeFactor, tC.n)
+			if tC.expectedStart != start {
+				subT.Errorf("expected the returned start to be %d, got %d", tC.expectedStart, start)
+			}
+
+			if tC.expectedCount != count {
+				subT.Errorf("expected the returned count to be %d, got %d", tC.expectedCount, count)
+			}
+
+			if count > 0 && !tC.queue.PopCommitN(savepoint, count) {
+				subT.Errorf("expected pop commit to pass normally but got commit failed")
+			}
+
+			start, count, _ = tC.queue.PopN(queueSizeFactor, tC.n)
+			if tC.nextExpectedStart != start {
+				subT.Errorf("expected the next returned start to be %d, got %d", tC.nextExpectedStart, start)
+			}
+
+			if tC.nextExpectedCount != count {
+				subT.Errorf("expected the next returned count to be %d, got %d", tC.nextExpectedCount, count)
+			}
+		})
+	}
+}
+
+func TestConcurrentBatchWork(t *testing.T) {
+	const queueSizeFactor = 6
+	q := NewQ()
+	const availableSlots = 1 << queueSizeFactor
+	jobs := [availableSlots]int{}
+
+	var wg sync.WaitGroup
+	wg.Add(2) // Add producer and consumer goroutines
+
+	// Producer
+	producedSum := 0
+	completedProducing := int32(0)
+	go func() {
+		defer func() {
+			atomic.AddInt32(&completedProducing, 1)
+			wg.Done()
+		}()
+
+		for i := 0; i < 1000; {
+			start, count := q.PushN(queueSizeFactor, 8)
+			if count == 0 {
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				continue
+			}
+
+			for slot := start; slot < start+count; slot++ {
+				jobs[slot] = i
+				producedSum += i
+				i++
+			}
+			q.PushCommitN(count)
+		}
+	}()
+
+	// Consumer
+	sum := 0
+	go func() {
+		defer wg.Done()
+
+		for {
+			completed := atomic.LoadInt32(&completedProducing) > 0
+			start, count, savepoint := q.PopN(queueSizeFactor, 8)
+			if count == 0 {
+				if completed {
+					break
+				}
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				continue
+			}
+
+			batchSum := 0
+			for i := start; i < start+count; i++ {
+				batchSum += jobs[i]
+			}
+			if !q.PopCommitN(savepoint, count) {
+				continue // Commit failed so we can't run the jobs
+			}
+			sum += batchSum
+		}
+	}()
+
+	wg.Wait()
+
+	if producedSum != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+}
@@ -11,4 +11,10 @@
 // the slice of jobs must be threadsafe in order to avoid this race condition.
 // Package nano is not safe to use in a multiple producer/multiple consumer scenario, however with a single producer
 // you may have multiple consumers.
+//
+// On top of Push and Pop, `nano.Q` has Peek, which returns the next position without claiming it, so a consumer can
+// inspect the job before deciding to claim it with PopCommitPeek, and PushN and PopN, which reserve contiguous ranges
+// of positions, so that a batch of jobs costs a single commit.
+// For queues that need more than 2^15 slots, `nano.Q64` is a `uint64` backed variant with 32 bit halves for the
+// head and tail. It only has Push, PushCommit, Pop, PopCommit, Len, Cap, IsEmpty and IsFull.
 package nano
@@ -0,0 +1,139 @@
+package nano
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// FuzzOps drives random sequences of operations with random factors, starting from random states,
+// and compares every result against a reference model of the queue.
+func FuzzOps(f *testing.F) {
+	f.Add(uint8(6), uint32(0), []byte{0, 0, 1, 2, 3, 1})
+	f.Add(uint8(6), uint32(0x7ff0), []byte{0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1})
+	f.Add(uint8(3), uint32(0x0005fffc), []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 254, 255, 1})
+	f.Add(uint8(15), uint32(0x12347fff), []byte{2, 6, 3, 7, 0, 1})
+
+	f.Fuzz(func(t *testing.T, rawFactor uint8, start uint32, ops []byte) {
+		factor := int(rawFactor%15) + 1
+		size := 1 << factor
+		capacity := size - 1
+		mask := size - 1
+
+		// Any tail is reachable, while the head is protected from overflowing past 0x8000 before every push
+		tailCounter := uint16(start >> 16)
+		headCounter := uint16(start) % 0x8001
+		q := Q(uint32(headCounter) | uint32(tailCounter)<<16)
+
+		modelHead := int(headCounter) & mask
+		modelTail := int(tailCounter) & mask
+		modelLen := (modelHead - modelTail) & mask
+
+		for i, op := range ops {
+			n := int(op>>2) + 1
+			switch op % 4 {
+			case 0:
+				idx, isFull := q.Push(factor)
+				if isFull != (modelLen == capacity) {
+					t.Fatalf("op %d: expected isFull to be %t, got %t", i, modelLen == capacity, isFull)
+				}
+
+				if isFull {
+					if idx != -1 {
+						t.Fatalf("op %d: expected the returned index to be -1 on a full queue, got %d", i, idx)
+					}
+					break
+				}
+
+				if idx != modelHead {
+					t.Fatalf("op %d: expected the returned index to be %d, got %d", i, modelHead, idx)
+				}
+
+				q.PushCommit()
+				modelHead = (modelHead + 1) & mask
+				modelLen++
+			case 1:
+				idx, savepoint, isEmpty := q.Pop(factor)
+				if isEmpty != (modelLen == 0) {
+					t.Fatalf("op %d: expected isEmpty to be %t, got %t", i, modelLen == 0, isEmpty)
+				}
+
+				if isEmpty {
+					if idx != -1 {
+						t.Fatalf("op %d: expected the returned index to be -1 on an empty queue, got %d", i, idx)
+					}
+					break
+				}
+
+				if idx != modelTail {
+					t.Fatalf("op %d: expected the returned index to be %d, got %d", i, modelTail, idx)
+				}
+
+				if !q.PopCommit(savepoint) {
+					t.Fatalf("op %d: expected pop commit to pass with a single consumer", i)
+				}
+				modelTail = (modelTail + 1) & mask
+				tailCounter++
+				modelLen--
+			case 2:
+				expected := minInt(n, capacity-modelLen, size-modelHead)
+				start, count := q.PushN(factor, n)
+				if count != expected {
+					t.Fatalf("op %d: expected the returned count to be %d, got %d", i, expected, count)
+				}
+
+				if count == 0 {
+					break
+				}
+
+				if start != modelHead {
+					t.Fatalf("op %d: expected the returned start to be %d, got %d", i, modelHead, start)
+				}
+
+				q.PushCommitN(count)
+				modelHead = (modelHead + count) & mask
+				modelLen += count
+			case 3:
+				expected := minInt(n, modelLen, size-modelTail)
+				start, count, savepoint := q.PopN(factor, n)
+				if count != expected {
+					t.Fatalf("op %d: expected the returned count to be %d, got %d", i, expected, count)
+				}
+
+				if count == 0 {
+					break
+				}
+
+				if start != modelTail {
+					t.Fatalf("op %d: expected the returned start to be %d, got %d", i, modelTail, start)
+				}
+
+				if !q.PopCommitN(savepoint, count) {
+					t.Fatalf("op %d: expected batch pop commit to pass with a single consumer", i)
+				}
+				modelTail = (modelTail + count) & mask
+				tailCounter += uint16(count)
+				modelLen -= count
+			}
+
+			// The head and tail never cross: the head never carries into the tail, and their distance is the length
+			acquired := atomic.LoadUint32((*uint32)(&q))
+			if uint16(acquired>>16) != tailCounter {
+				t.Fatalf("op %d: expected the tail counter to be %d, got %d", i, tailCounter, uint16(acquired>>16))
+			}
+
+			if l := int((acquired - acquired>>16) & uint32(mask)); l != modelLen {
+				t.Fatalf("op %d: expected the length to be %d, got %d", i, modelLen, l)
+			}
+		}
+	})
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
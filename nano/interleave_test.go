@@ -0,0 +1,224 @@
+package nano
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/probably-not/q/internal/consts"
+	"github.com/probably-not/q/internal/interleave"
+)
+
+// scheduledQ runs the operations of Q on a state Word, step by step as Q runs them on its atomic state,
+// and with the same decoding of the acquired state. TestScheduledQMatchesQ keeps the two in step.
+type scheduledQ struct {
+	w      interleave.Word
+	factor int
+}
+
+func (q scheduledQ) Pop() (int, uint32, bool) {
+	return popAt(q.w.Load(), q.factor)
+}
+
+func (q scheduledQ) PopCommit(savepoint uint32) bool {
+	return q.w.CompareAndSwap(savepoint, uint32(savepoint+consts.CommitPopU32))
+}
+
+func (q scheduledQ) Push() (int, bool) {
+	acquired := q.w.Load()
+	if acquired&consts.PushOverflowCheckU32 != 0 {
+		q.w.Add(consts.PushOverflowProtectionU32)
+	}
+
+	return pushAt(acquired, q.factor)
+}
+
+func (q scheduledQ) PushCommit() {
+	q.w.Add(1)
+}
+
+func TestInterleavings(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		factor    int
+		state     uint32
+		jobs      int
+		consumers int
+		attempts  int
+	}{
+		{
+			desc:      "Two consumers race for a single job",
+			factor:    1,
+			state:     0,
+			jobs:      1,
+			consumers: 2,
+			attempts:  2,
+		},
+		{
+			desc:      "Two consumers race for jobs that wrap around the ring",
+			factor:    1,
+			state:     0x00010001,
+			jobs:      2,
+			consumers: 2,
+			attempts:  2,
+		},
+		{
+			desc:      "Two consumers race for jobs while the producer fills the ring",
+			factor:    2,
+			state:     0,
+			jobs:      3,
+			consumers: 2,
+			attempts:  1,
+		},
+		{
+			desc:      "Two consumers race for jobs while the head is protected from overflowing",
+			factor:    2,
+			state:     0x7fff7fff,
+			jobs:      2,
+			consumers: 2,
+			attempts:  1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			runs, err := interleave.Explore(func(s *interleave.Scheduler) func() error {
+				// The goroutines run the operations of Q on a scheduled state word
+				w := s.NewWord(tC.state)
+				q := scheduledQ{w: w, factor: tC.factor}
+				slots := make([]int, 1<<tC.factor)
+				pushed := 0
+				var delivered []int
+
+				// Producer, which gives up on a job after a bounded number of attempts, so that every schedule ends
+				s.Go(func() {
+					for job := 1; job <= tC.jobs; job++ {
+						for attempt := 0; attempt < tC.attempts; attempt++ {
+							pos, isFull := q.Push()
+							if isFull {
+								continue
+							}
+
+							s.Yield()
+							slots[pos] = job
+							pushed++
+							q.PushCommit()
+							break
+						}
+					}
+				})
+
+				// Consumers
+				for c := 0; c < tC.consumers; c++ {
+					s.Go(func() {
+						for attempt := 0; attempt < tC.attempts; attempt++ {
+							pos, savepoint, isEmpty := q.Pop()
+							if isEmpty {
+								continue
+							}
+
+							s.Yield()
+							job := slots[pos]
+							if !q.PopCommit(savepoint) {
+								continue // Commit failed so we can't run the job
+							}
+
+							delivered = append(delivered, job)
+						}
+					})
+				}
+
+				return func() error {
+					// Jobs are pushed in order, so the committed pops must deliver them in order, each exactly once
+					for i, job := range delivered {
+						if job != i+1 {
+							return fmt.Errorf("expected delivery number %d to be job %d, got job %d (deliveries %v)", i, i+1, job, delivered)
+						}
+					}
+
+					left := 0
+					for pos, savepoint, isEmpty := q.Pop(); !isEmpty; pos, savepoint, isEmpty = q.Pop() {
+						if slots[pos] != len(delivered)+left+1 {
+							return fmt.Errorf("expected the job left at position %d to be %d, got %d", pos, len(delivered)+left+1, slots[pos])
+						}
+						q.PopCommit(savepoint)
+						left++
+					}
+
+					if len(delivered)+left != pushed {
+						return fmt.Errorf("expected %d jobs to be delivered or left in the queue, got %d delivered and %d left", pushed, len(delivered), left)
+					}
+					return nil
+				}
+			})
+			if err != nil {
+				subT.Errorf("unexpected error after %d interleavings: %v", runs, err)
+			}
+
+			subT.Logf("explored %d interleavings", runs)
+		})
+	}
+}
+
+func TestScheduledQMatchesQ(t *testing.T) {
+	const factor = 2
+	const depth = 6
+	ops := []string{"Push", "PushCommit", "Pop", "PopCommit"}
+	testCases := []struct {
+		desc  string
+		state uint32
+	}{
+		{
+			desc:  "Empty queue",
+			state: 0,
+		},
+		{
+			desc:  "Queue at the end of the ring",
+			state: 0x00030003,
+		},
+		{
+			desc:  "Head about to be protected from overflowing",
+			state: 0x7fff7ffe,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			sequences := 1
+			for i := 0; i < depth; i++ {
+				sequences *= len(ops)
+			}
+
+			// Every sequence of operations must return the same results and leave the same state on both queues
+			for seq := 0; seq < sequences; seq++ {
+				q := Q(tC.state)
+				w := interleave.Atomic(tC.state)
+				sq := scheduledQ{w: &w, factor: factor}
+				var savepoint, scheduledSavepoint uint32
+				var run []string
+
+				for i, op := 0, seq; i < depth; i, op = i+1, op/len(ops) {
+					run = append(run, ops[op%len(ops)])
+
+					var expected, got string
+					switch op % len(ops) {
+					case 0:
+						expected, got = fmt.Sprint(q.Push(factor)), fmt.Sprint(sq.Push())
+					case 1:
+						q.PushCommit()
+						sq.PushCommit()
+					case 2:
+						var pos, scheduledPos int
+						var isEmpty, scheduledIsEmpty bool
+						pos, savepoint, isEmpty = q.Pop(factor)
+						scheduledPos, scheduledSavepoint, scheduledIsEmpty = sq.Pop()
+						expected, got = fmt.Sprint(pos, savepoint, isEmpty), fmt.Sprint(scheduledPos, scheduledSavepoint, scheduledIsEmpty)
+					case 3:
+						expected, got = fmt.Sprint(q.PopCommit(savepoint)), fmt.Sprint(sq.PopCommit(scheduledSavepoint))
+					}
+
+					if expected != got || uint32(q) != w.Load() {
+						subT.Fatalf("expected %v to return %s with state %#x, got %s with state %#x", run, expected, uint32(q), got, w.Load())
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package nano
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/probably-not/q/internal/lincheck"
+)
+
+func TestLinearizable(t *testing.T) {
+	const queueSizeFactor = 3 // Small queue so that the history wraps around and hits full and empty results
+	const availableSlots = 1 << queueSizeFactor
+	q := NewQ()
+	// Consumers may read a slot that the producer is writing to, so all access is atomic.
+	jobs := [availableSlots]int64{}
+	rec := lincheck.NewRecorder()
+
+	var wg sync.WaitGroup
+	wg.Add(1) // Add producer goroutine
+
+	// Producer
+	completedProducing := int32(0)
+	go func() {
+		defer func() {
+			atomic.AddInt32(&completedProducing, 1)
+			wg.Done()
+		}()
+
+		for i := 0; i < 200; {
+			call := rec.Now()
+			slot, isFull := q.Push(queueSizeFactor)
+			if isFull {
+				rec.Record(lincheck.Operation{Kind: lincheck.Push, Call: call, Return: rec.Now()})
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				continue
+			}
+
+			atomic.StoreInt64(&jobs[slot], int64(i))
+			q.PushCommit()
+			rec.Record(lincheck.Operation{Kind: lincheck.Push, Value: i, Ok: true, Call: call, Return: rec.Now()})
+			i++
+		}
+	}()
+
+	// Consumers
+	for c := 0; c < 4; c++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				completed := atomic.LoadInt32(&completedProducing) > 0
+				call := rec.Now()
+				slot, savepoint, isEmpty := q.Pop(queueSizeFactor)
+				for !isEmpty {
+					job := atomic.LoadInt64(&jobs[slot])
+					if q.PopCommit(savepoint) {
+						rec.Record(lincheck.Operation{Kind: lincheck.Pop, Value: int(job), Ok: true, Call: call, Return: rec.Now()})
+						break
+					}
+					// Commit failed, so retry within the same operation
+					slot, savepoint, isEmpty = q.Pop(queueSizeFactor)
+				}
+
+				if isEmpty {
+					rec.Record(lincheck.Operation{Kind: lincheck.Pop, Call: call, Return: rec.Now()})
+					if completed {
+						return
+					}
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if !lincheck.Check(rec.History(), availableSlots-1) {
+		t.Errorf("expected the history of %d operations to be linearizable", len(rec.History()))
+	}
+}
@@ -0,0 +1,38 @@
+package nano
+
+import (
+	"sync/atomic"
+
+	"github.com/probably-not/q/internal/consts"
+)
+
+// Peek will calculate the position that can currently be popped from the queue, without claiming it.
+// It returns the position, a token holding the version of the queue when it was peeked,
+// along with a boolean indicating if the queue is empty or not.
+// If the queue is empty, `-1, 0, true` will be returned.
+// If the queue is not empty, `pos, token, false` will be returned.
+// The job at the position can be inspected to decide whether the caller should handle it,
+// and then claimed with PopCommitPeek, or simply left in the queue for another consumer.
+// Peek is guaranteed to have no side effects on the queue.
+func (q *Q) Peek(factor int) (int, uint32, bool) {
+	return q.Pop(factor)
+}
+
+// PopCommitPeek will claim the position returned by a previous Peek operation.
+// It requires the token that was returned by the Peek operation, and succeeds only if no consumer
+// has popped from the queue since the Peek. Unlike PopCommit, pushes that were committed since the
+// Peek do not cause the commit to fail.
+// If the commit succeeds, `true` is returned to indicate that the caller does in fact
+// have the job that it inspected after the Peek operation.
+func (q *Q) PopCommitPeek(token uint32) bool {
+	for {
+		acquired := atomic.LoadUint32((*uint32)(q))
+		if acquired>>16 != token>>16 {
+			return false
+		}
+
+		if atomic.CompareAndSwapUint32((*uint32)(q), acquired, acquired+consts.CommitPopU32) {
+			return true
+		}
+	}
+}
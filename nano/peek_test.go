@@ -0,0 +1,82 @@
+package nano
+
+import "testing"
+
+func TestPeek(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		pushes            int
+		expectedIdx       int
+		expectedIsEmpty   bool
+		pushBeforeCommit  bool
+		popBeforeCommit   bool
+		expectedCommitted bool
+	}{
+		{
+			desc:            "Zero value of queue is empty",
+			pushes:          0,
+			expectedIdx:     -1,
+			expectedIsEmpty: true,
+		},
+		{
+			desc:              "Peeked position is claimed when nothing changed",
+			pushes:            2,
+			expectedIdx:       0,
+			expectedCommitted: true,
+		},
+		{
+			desc:              "Peeked position is claimed when the producer pushed in the meantime",
+			pushes:            2,
+			expectedIdx:       0,
+			pushBeforeCommit:  true,
+			expectedCommitted: true,
+		},
+		{
+			desc:              "Peeked position is not claimed when another consumer popped in the meantime",
+			pushes:            2,
+			expectedIdx:       0,
+			popBeforeCommit:   true,
+			expectedCommitted: false,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			const queueSizeFactor = 6
+			q := NewQ()
+			for i := 0; i < tC.pushes; i++ {
+				q.PushCommit()
+			}
+
+			idx, token, isEmpty := q.Peek(queueSizeFactor)
+			if tC.expectedIdx != idx {
+				subT.Errorf("expected the returned index to be %d, got %d", tC.expectedIdx, idx)
+			}
+
+			if tC.expectedIsEmpty != isEmpty {
+				subT.Errorf("expected isEmpty to be %t, got %t", tC.expectedIsEmpty, isEmpty)
+			}
+
+			if isEmpty {
+				return
+			}
+
+			if again, _, _ := q.Peek(queueSizeFactor); again != idx {
+				subT.Errorf("expected peeking again to return %d, got %d", idx, again)
+			}
+
+			if tC.pushBeforeCommit {
+				q.Push(queueSizeFactor)
+				q.PushCommit()
+			}
+
+			if tC.popBeforeCommit {
+				_, savepoint, _ := q.Pop(queueSizeFactor)
+				q.PopCommit(savepoint)
+			}
+
+			if committed := q.PopCommitPeek(token); tC.expectedCommitted != committed {
+				subT.Errorf("expected committed to be %t, got %t", tC.expectedCommitted, committed)
+			}
+		})
+	}
+}
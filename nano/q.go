@@ -3,15 +3,33 @@ package nano
 import (
 	"sync/atomic"
 
+	"github.com/probably-not/q/internal/check"
 	"github.com/probably-not/q/internal/consts"
 )
 
+var (
+	// ErrFactorTooLarge is returned when the size factor does not fit in the halves of the queue state.
+	ErrFactorTooLarge = check.ErrFactorTooLarge
+	// ErrFactorZero is returned when the size factor is zero or negative.
+	ErrFactorZero = check.ErrFactorZero
+)
+
 type Q uint32
 
 func NewQ() Q {
 	return 0
 }
 
+// NewQChecked creates a new queue after validating the size factor that will be passed to its operations.
+// If the factor can't be used with the queue, ErrFactorZero or ErrFactorTooLarge is returned.
+func NewQChecked(factor int) (Q, error) {
+	if err := check.FactorU32(factor); err != nil {
+		return 0, err
+	}
+
+	return NewQ(), nil
+}
+
 // Pop will calculate the position that can currently be popped from the queue.
 // It returns the position, a save point (to allow ensuring the commit),
 // along with a boolean indicating if the queue is empty or not.
@@ -21,16 +39,8 @@ func NewQ() Q {
 // order to ensure that the job is truly the caller's job, and it has not been
 // committed by another consumer.
 func (q *Q) Pop(factor int) (int, uint32, bool) {
-	acquired := atomic.LoadUint32((*uint32)(q))
-	mask := (uint32(1) << factor) - 1
-	head := acquired & mask
-	tail := acquired >> 16 & mask
-
-	if head == tail {
-		return -1, 0, true
-	}
-
-	return int(tail), acquired, false
+	check.AssertFactorU32(factor)
+	return popAt(atomic.LoadUint32((*uint32)(q)), factor)
 }
 
 // PopCommit will commit the previously executed Pop operation to the queue.
@@ -48,25 +58,86 @@ func (q *Q) PopCommit(savepoint uint32) bool {
 // If the queue is full, `-1, true` will be returned.
 // If the queue is not full, `pos, false` will be returned.
 func (q *Q) Push(factor int) (int, bool) {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	if acquired&consts.PushOverflowCheckU32 != 0 {
+		atomic.AddUint32((*uint32)(q), consts.PushOverflowProtectionU32)
+	}
+
+	return pushAt(acquired, factor)
+}
+
+// PushCommit will commit the previously executed Push operation to the queue.
+// This moves the index of the queue to the next push-able index.
+func (q *Q) PushCommit() {
+	atomic.AddUint32((*uint32)(q), 1)
+}
+
+// Len returns the number of jobs that are committed to the queue and not yet popped.
+// It is decoded from a single atomic load, and unlike a Push probe it has no side effects.
+func (q *Q) Len(factor int) int {
+	check.AssertFactorU32(factor)
 	acquired := atomic.LoadUint32((*uint32)(q))
 	mask := (uint32(1) << factor) - 1
 	head := acquired & mask
 	tail := acquired >> 16 & mask
-	next := (head + uint32(1)) & mask
 
-	if acquired&consts.PushOverflowCheckU32 != 0 {
-		atomic.AddUint32((*uint32)(q), consts.PushOverflowProtectionU32)
+	return int((head - tail) & mask)
+}
+
+// Cap returns the number of jobs that the queue can hold, which is one less than its number of positions,
+// since a full queue always keeps one position free to tell it apart from an empty queue.
+func (q *Q) Cap(factor int) int {
+	check.AssertFactorU32(factor)
+	return (1 << factor) - 1
+}
+
+// IsEmpty reports whether the queue is empty, from a single atomic load and without side effects.
+func (q *Q) IsEmpty(factor int) bool {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	return head == tail
+}
+
+// IsFull reports whether the queue is full, from a single atomic load and without side effects.
+func (q *Q) IsFull(factor int) bool {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	return (head+uint32(1))&mask == tail
+}
+
+// popAt decodes the position that can be popped from an acquired state of the queue.
+// It is kept apart from the atomic operations so that the interleaving tests run the same decoding as Pop.
+func popAt(acquired uint32, factor int) (int, uint32, bool) {
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	if head == tail {
+		return -1, 0, true
 	}
 
+	return int(tail), acquired, false
+}
+
+// pushAt decodes the position that can be pushed to from an acquired state of the queue.
+func pushAt(acquired uint32, factor int) (int, bool) {
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+	next := (head + uint32(1)) & mask
+
 	if next == tail {
 		return -1, true
 	}
 
 	return int(head), false
 }
-
-// PushCommit will commit the previously executed Push operation to the queue.
-// This moves the index of the queue to the next push-able index.
-func (q *Q) PushCommit() {
-	atomic.AddUint32((*uint32)(q), 1)
-}
@@ -0,0 +1,434 @@
+package nano
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPop64(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		expectedAllowedPops int
+		expectedIdx         int
+		queueSizeFactor     int
+		queue               Q64
+		expectedIsEmpty     bool
+	}{
+		{
+			desc:            "Zero value of queue is empty",
+			queue:           NewQ64(),
+			expectedIdx:     -1,
+			expectedIsEmpty: true,
+			queueSizeFactor: 6,
+		},
+		{
+			desc: "Pops are allowed as many times as there are jobs in the queue, and when completed return empty",
+			queue: func() Q64 {
+				q := NewQ64()
+				for i := 0; i < 10; i++ {
+					q.PushCommit() // 10 pushes
+				}
+				return q
+			}(),
+			expectedAllowedPops: 10,
+			expectedIdx:         -1,
+			expectedIsEmpty:     true,
+			queueSizeFactor:     6,
+		},
+		{
+			desc: "Pops are allowed as many times as there are jobs in the queue, and when not completed return the next index",
+			queue: func() Q64 {
+				q := NewQ64()
+				for i := 0; i < 10; i++ {
+					q.PushCommit() // 10 pushes
+				}
+				return q
+			}(),
+			expectedAllowedPops: 8,
+			expectedIdx:         8,
+			expectedIsEmpty:     false,
+			queueSizeFactor:     6,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			queue := new(Q64) // Allocated on its own, so that it is 64 bit aligned on 32 bit platforms
+			*queue = tC.queue
+
+			for i := 0; i < tC.expectedAllowedPops; i++ {
+				idx, savepoint, isEmpty := queue.Pop(tC.queueSizeFactor)
+				if idx == -1 || isEmpty {
+					subT.Errorf("unexpected empty queue during allowed pops at pop number %d", i)
+					return
+				}
+
+				if i != idx {
+					subT.Errorf("expected popped job to be %d but got %d", i, idx)
+				}
+
+				if !queue.PopCommit(savepoint) {
+					subT.Errorf("expected pop commit to pass normally on job %d but got commit failed", i)
+				}
+			}
+
+			idx, _, isEmpty := queue.Pop(tC.queueSizeFactor)
+			if tC.expectedIdx != idx {
+				subT.Errorf("expected the returned index to be %d, got %d", tC.expectedIdx, idx)
+			}
+
+			if tC.expectedIsEmpty != isEmpty {
+				subT.Errorf("expected isEmpty to be %t, got %t", tC.expectedIsEmpty, isEmpty)
+			}
+		})
+	}
+}
+
+func TestPush64(t *testing.T) {
+	randomAmountOfJobs := rand.Intn(62)
+
+	testCases := []struct {
+		desc               string
+		queue              Q64
+		expectedIsFull     bool
+		nextExpectedIsFull bool
+		expectedIdx        int
+		nextExpectedIdx    int
+		queueSizeFactor    int
+	}{
+		{
+			desc:               "Zero value of queue allows pushing",
+			queue:              NewQ64(),
+			expectedIsFull:     false,
+			nextExpectedIsFull: false,
+			expectedIdx:        0,
+			nextExpectedIdx:    1,
+			queueSizeFactor:    6,
+		},
+		{
+			desc:               "Queue with random amount of jobs less than size factor allows pushing",
+			queue:              Q64(randomAmountOfJobs),
+			expectedIsFull:     false,
+			nextExpectedIsFull: false,
+			expectedIdx:        randomAmountOfJobs,
+			nextExpectedIdx:    randomAmountOfJobs + 1,
+			queueSizeFactor:    6,
+		},
+		{
+			desc:               "Queue at the size factor is marked as full and cannot be pushed to",
+			queue:              Q64(63),
+			expectedIsFull:     true,
+			nextExpectedIsFull: false,
+			expectedIdx:        -1,
+			nextExpectedIdx:    0,
+			queueSizeFactor:    6,
+		},
+		{
+			desc:               "Overflow is protected against",
+			queue:              Q64(18446744073709551615),
+			expectedIsFull:     false,
+			nextExpectedIsFull: false,
+			expectedIdx:        63,
+			nextExpectedIdx:    0,
+			queueSizeFactor:    6,
+		},
+		{
+			desc:               "Queue with a factor beyond the limit of Q fills at its full capacity",
+			queue:              Q64(1<<20 - 2),
+			expectedIsFull:     false,
+			nextExpectedIsFull: true,
+			expectedIdx:        1<<20 - 2,
+			nextExpectedIdx:    -1,
+			queueSizeFactor:    20,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			queue := new(Q64) // Allocated on its own, so that it is 64 bit aligned on 32 bit platforms
+			*queue = tC.queue
+
+			idx, isFull := queue.Push(tC.queueSizeFactor)
+			if tC.expectedIdx != idx {
+				subT.Errorf("expected the returned index to be %d, got %d", tC.expectedIdx, idx)
+			}
+
+			if tC.expectedIsFull != isFull {
+				subT.Errorf("expected isFull to be %t, got %t", tC.expectedIsFull, isFull)
+			}
+
+			queue.PushCommit()
+
+			idx, isFull = queue.Push(tC.queueSizeFactor)
+			if tC.nextExpectedIdx != idx {
+				subT.Errorf("expected the next returned index to be %d, got %d", tC.nextExpectedIdx, idx)
+			}
+
+			if tC.nextExpectedIsFull != isFull {
+				subT.Errorf("expected next isFull to be %t, got %t", tC.nextExpectedIsFull, isFull)
+			}
+		})
+	}
+}
+
+func TestConcurrentWorkSingleConsumer64(t *testing.T) {
+	q := NewQ64()
+	const queueSizeFactor = 6
+	const availableSlots = 1 << queueSizeFactor
+	jobs := [availableSlots]int{}
+
+	var wg sync.WaitGroup
+	wg.Add(2) // Add producer and consumer goroutines
+
+	// Producer
+	producedSum := 0
+	completedProducing := int32(0)
+	go func() {
+		defer func() {
+			atomic.AddInt32(&completedProducing, 1)
+			wg.Done()
+		}()
+		fullAttempts := 0
+
+		for i := 0; i < 1000; i++ {
+			slot, isFull := q.Push(queueSizeFactor)
+			if isFull {
+				fullAttempts++
+				if fullAttempts > 1000 {
+					break
+				}
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				continue
+			}
+
+			jobs[slot] = i
+			producedSum += i
+			q.PushCommit()
+		}
+	}()
+
+	// Consumer
+	sum := 0
+	go func() {
+		defer wg.Done()
+
+		for {
+			slot, savepoint, isEmpty := q.Pop(queueSizeFactor)
+			if isEmpty {
+				if atomic.LoadInt32(&completedProducing) > 0 {
+					break
+				}
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				continue
+			}
+
+			job := jobs[slot]
+			if !q.PopCommit(savepoint) {
+				continue // Commit failed so we can't run the job
+			}
+			sum += job
+		}
+	}()
+
+	wg.Wait()
+
+	if producedSum != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+}
+
+func TestConcurrentWorkMultipleConsumers64(t *testing.T) {
+	q := NewQ64()
+	const queueSizeFactor = 6
+	const availableSlots = 1 << queueSizeFactor
+	// [availableSlots]int64{} is the underlying type of the atomic value.
+	atomicJobs := &atomic.Value{}
+	atomicJobs.Store([availableSlots]int64{})
+
+	var wg sync.WaitGroup
+	wg.Add(1) // Add producer goroutine
+
+	// Producer
+	producedSum := int64(0)
+	completedProducing := int32(0)
+	go func() {
+		defer func() {
+			atomic.AddInt32(&completedProducing, 1)
+			wg.Done()
+		}()
+		fullAttempts := 0
+
+		for i := int64(0); i < 1000; i++ {
+			slot, isFull := q.Push(queueSizeFactor)
+			if isFull {
+				fullAttempts++
+				if fullAttempts > 1000 {
+					break
+				}
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				continue
+			}
+
+			jobs := atomicJobs.Load().([availableSlots]int64)
+			newJobs := jobs
+			newJobs[slot] = i
+			if atomicJobs.CompareAndSwap(jobs, newJobs) {
+				producedSum += i
+			}
+			q.PushCommit()
+		}
+	}()
+
+	// Consumer
+	sum := int64(0)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				slot, savepoint, isEmpty := q.Pop(queueSizeFactor)
+				if isEmpty {
+					if atomic.LoadInt32(&completedProducing) > 0 {
+						break
+					}
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					continue
+				}
+
+				jobs := atomicJobs.Load().([availableSlots]int64)
+				job := jobs[slot]
+				if !q.PopCommit(savepoint) {
+					continue // Commit failed so we can't run the job
+				}
+
+				atomic.AddInt64(&sum, job)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if producedSum < 1000 {
+		t.Errorf("expected the produced sum to be greater than 1000 but got %d", producedSum)
+	}
+
+	if producedSum != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+}
+
+func TestNewQ64Checked(t *testing.T) {
+	testCases := []struct {
+		expectedErr     error
+		desc            string
+		queueSizeFactor int
+	}{
+		{
+			desc:            "Factor within the supported range is accepted",
+			queueSizeFactor: 6,
+			expectedErr:     nil,
+		},
+		{
+			desc:            "Largest supported factor is accepted",
+			queueSizeFactor: 31,
+			expectedErr:     nil,
+		},
+		{
+			desc:            "Factor larger than the 32 bit halves allow is rejected",
+			queueSizeFactor: 32,
+			expectedErr:     ErrFactorTooLarge,
+		},
+		{
+			desc:            "Zero factor is rejected",
+			queueSizeFactor: 0,
+			expectedErr:     ErrFactorZero,
+		},
+		{
+			desc:            "Negative factor is rejected",
+			queueSizeFactor: -1,
+			expectedErr:     ErrFactorZero,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			_, err := NewQ64Checked(tC.queueSizeFactor)
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestIntrospection64(t *testing.T) {
+	const queueSizeFactor = 6
+
+	testCases := []struct {
+		desc            string
+		expectedLen     int
+		queue           Q64
+		expectedIsEmpty bool
+		expectedIsFull  bool
+	}{
+		{
+			desc:            "Zero value of queue is empty",
+			queue:           Q64(0),
+			expectedLen:     0,
+			expectedIsEmpty: true,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue with jobs reports their count",
+			queue:           Q64(10),
+			expectedLen:     10,
+			expectedIsEmpty: false,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue at the size factor is full",
+			queue:           Q64(63),
+			expectedLen:     63,
+			expectedIsEmpty: false,
+			expectedIsFull:  true,
+		},
+		{
+			desc:            "Queue that has wrapped around reports the jobs across the boundary",
+			queue:           Q64(60<<32 | 4),
+			expectedLen:     8,
+			expectedIsEmpty: false,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue that has overflowed is empty",
+			queue:           Q64(18446744073709551615),
+			expectedLen:     0,
+			expectedIsEmpty: true,
+			expectedIsFull:  false,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			queue := new(Q64) // Allocated on its own, so that it is 64 bit aligned on 32 bit platforms
+			*queue = tC.queue
+
+			if l := queue.Len(queueSizeFactor); tC.expectedLen != l {
+				subT.Errorf("expected the length to be %d, got %d", tC.expectedLen, l)
+			}
+
+			if c := queue.Cap(queueSizeFactor); c != 63 {
+				subT.Errorf("expected the capacity to be %d, got %d", 63, c)
+			}
+
+			if isEmpty := queue.IsEmpty(queueSizeFactor); tC.expectedIsEmpty != isEmpty {
+				subT.Errorf("expected isEmpty to be %t, got %t", tC.expectedIsEmpty, isEmpty)
+			}
+
+			if isFull := queue.IsFull(queueSizeFactor); tC.expectedIsFull != isFull {
+				subT.Errorf("expected isFull to be %t, got %t", tC.expectedIsFull, isFull)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package nano
 
 import (
+	"errors"
 	"math/rand"
 	"sync"
 	"sync/atomic"
@@ -304,3 +305,112 @@ func TestConcurrentWorkMultipleConsumers(t *testing.T) {
 		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
 	}
 }
+
+func TestNewQChecked(t *testing.T) {
+	testCases := []struct {
+		expectedErr     error
+		desc            string
+		queueSizeFactor int
+	}{
+		{
+			desc:            "Factor within the supported range is accepted",
+			queueSizeFactor: 6,
+			expectedErr:     nil,
+		},
+		{
+			desc:            "Largest supported factor is accepted",
+			queueSizeFactor: 15,
+			expectedErr:     nil,
+		},
+		{
+			desc:            "Factor larger than the 16 bit halves allow is rejected",
+			queueSizeFactor: 16,
+			expectedErr:     ErrFactorTooLarge,
+		},
+		{
+			desc:            "Zero factor is rejected",
+			queueSizeFactor: 0,
+			expectedErr:     ErrFactorZero,
+		},
+		{
+			desc:            "Negative factor is rejected",
+			queueSizeFactor: -1,
+			expectedErr:     ErrFactorZero,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			_, err := NewQChecked(tC.queueSizeFactor)
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	const queueSizeFactor = 6
+
+	testCases := []struct {
+		desc            string
+		expectedLen     int
+		queue           Q
+		expectedIsEmpty bool
+		expectedIsFull  bool
+	}{
+		{
+			desc:            "Zero value of queue is empty",
+			queue:           Q(0),
+			expectedLen:     0,
+			expectedIsEmpty: true,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue with jobs reports their count",
+			queue:           Q(10),
+			expectedLen:     10,
+			expectedIsEmpty: false,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue at the size factor is full",
+			queue:           Q(63),
+			expectedLen:     63,
+			expectedIsEmpty: false,
+			expectedIsFull:  true,
+		},
+		{
+			desc:            "Queue that has wrapped around reports the jobs across the boundary",
+			queue:           Q(60<<16 | 4),
+			expectedLen:     8,
+			expectedIsEmpty: false,
+			expectedIsFull:  false,
+		},
+		{
+			desc:            "Queue that has overflowed is empty",
+			queue:           Q(4294967295),
+			expectedLen:     0,
+			expectedIsEmpty: true,
+			expectedIsFull:  false,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			if l := tC.queue.Len(queueSizeFactor); tC.expectedLen != l {
+				subT.Errorf("expected the length to be %d, got %d", tC.expectedLen, l)
+			}
+
+			if c := tC.queue.Cap(queueSizeFactor); c != 63 {
+				subT.Errorf("expected the capacity to be %d, got %d", 63, c)
+			}
+
+			if isEmpty := tC.queue.IsEmpty(queueSizeFactor); tC.expectedIsEmpty != isEmpty {
+				subT.Errorf("expected isEmpty to be %t, got %t", tC.expectedIsEmpty, isEmpty)
+			}
+
+			if isFull := tC.queue.IsFull(queueSizeFactor); tC.expectedIsFull != isFull {
+				subT.Errorf("expected isFull to be %t, got %t", tC.expectedIsFull, isFull)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package pico
+
+import (
+	"sync/atomic"
+
+	"github.com/probably-not/q/internal/check"
+	"github.com/probably-not/q/internal/consts"
+)
+
+// PushN will calculate a contiguous range of positions that can currently be pushed to in the queue.
+// It returns the first position of the range, along with the number of positions in the range, which is at most n.
+// The range never wraps around the end of the queue, so a range that would cross the mask boundary is cut short,
+// and a following PushN returns the rest starting from position 0.
+// If the queue is full, `-1, 0` will be returned.
+// If the queue is not full, `start, count` will be returned, and PushCommitN must be called with the count.
+func (q *Q) PushN(factor int, n int) (int, int) {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	if acquired&consts.PushOverflowCheckU32 != 0 {
+		atomic.AddUint32((*uint32)(q), consts.PushOverflowProtectionU32)
+	}
+
+	count := minCount(n, (tail-head-1)&mask, mask+1-head)
+	if count == 0 {
+		return -1, 0
+	}
+
+	return int(head), count
+}
+
+// PushCommitN will commit the previously executed PushN operation to the queue.
+// This moves the index of the queue past the count of positions that were pushed.
+func (q *Q) PushCommitN(count int) {
+	atomic.AddUint32((*uint32)(q), uint32(count))
+}
+
+// PopN will calculate a contiguous range of positions that can currently be popped from the queue.
+// It returns the first position of the range, along with the number of positions in the range, which is at most n.
+// The range never wraps around the end of the queue, so a range that would cross the mask boundary is cut short,
+// and a following PopN returns the rest starting from position 0.
+// If the queue is empty, `-1, 0` will be returned.
+// If the queue is not empty, `start, count` will be returned, and PopCommitN must be called with the count.
+func (q *Q) PopN(factor int, n int) (int, int) {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	count := minCount(n, (head-tail)&mask, mask+1-tail)
+	if count == 0 {
+		return -1, 0
+	}
+
+	return int(tail), count
+}
+
+// PopCommitN will commit the previously executed PopN operation to the queue.
+// This moves the index of the queue past the count of positions that were popped.
+func (q *Q) PopCommitN(count int) {
+	atomic.AddUint32((*uint32)(q), uint32(count)*consts.CommitPopU32)
+}
+
+// minCount returns the smallest of the requested count and the available counts, treating a non-positive request as zero.
+func minCount(n int, available, untilBoundary uint32) int {
+	if n <= 0 {
+		return 0
+	}
+
+	count := uint32(n)
+	if uint64(n) > uint64(available) {
+		count = available
+	}
+
+	if count > untilBoundary {
+		count = untilBoundary
+	}
+
+	return int(count)
+}
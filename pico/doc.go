@@ -5,4 +5,9 @@
 // managed by the `pico.Q` type.
 // Package pico is not safe to use in a multiple producer/multiple consumer scenario, as the time between the
 // <Op> and <Op>Commit operations is not managed and is therefore racy.
+//
+// On top of Push and Pop, `pico.Q` has PushN and PopN, which reserve contiguous ranges of positions, so that a batch
+// of jobs costs a single commit.
+// For queues that need more than 2^15 slots, `pico.Q64` is a `uint64` backed variant with 32 bit halves for the
+// head and tail. It only has Push, PushCommit, Pop, PopCommit, Len, Cap, IsEmpty and IsFull.
 package pico
@@ -0,0 +1,75 @@
+package pico
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/probably-not/q/internal/lincheck"
+)
+
+func TestLinearizable(t *testing.T) {
+	const queueSizeFactor = 3 // Small queue so that the history wraps around and hits full and empty results
+	const availableSlots = 1 << queueSizeFactor
+	q := NewQ()
+	// With a single consumer, a slot is only read after the producer's commit, so the slots need no atomic access.
+	jobs := [availableSlots]int{}
+	rec := lincheck.NewRecorder()
+
+	var wg sync.WaitGroup
+	wg.Add(2) // Add producer and consumer goroutines
+
+	// Producer
+	completedProducing := int32(0)
+	go func() {
+		defer func() {
+			atomic.AddInt32(&completedProducing, 1)
+			wg.Done()
+		}()
+
+		for i := 0; i < 200; {
+			call := rec.Now()
+			slot, isFull := q.Push(queueSizeFactor)
+			if isFull {
+				rec.Record(lincheck.Operation{Kind: lincheck.Push, Call: call, Return: rec.Now()})
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				continue
+			}
+
+			jobs[slot] = i
+			q.PushCommit()
+			rec.Record(lincheck.Operation{Kind: lincheck.Push, Value: i, Ok: true, Call: call, Return: rec.Now()})
+			i++
+		}
+	}()
+
+	// Consumer
+	go func() {
+		defer wg.Done()
+
+		for {
+			completed := atomic.LoadInt32(&completedProducing) > 0
+			call := rec.Now()
+			slot, isEmpty := q.Pop(queueSizeFactor)
+			if isEmpty {
+				rec.Record(lincheck.Operation{Kind: lincheck.Pop, Call: call, Return: rec.Now()})
+				if completed {
+					return
+				}
+				<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+				continue
+			}
+
+			job := jobs[slot]
+			q.PopCommit()
+			rec.Record(lincheck.Operation{Kind: lincheck.Pop, Value: job, Ok: true, Call: call, Return: rec.Now()})
+		}
+	}()
+
+	wg.Wait()
+
+	if !lincheck.Check(rec.History(), availableSlots-1) {
+		t.Errorf("expected the history of %d operations to be linearizable", len(rec.History()))
+	}
+}
@@ -3,20 +3,39 @@ package pico
 import (
 	"sync/atomic"
 
+	"github.com/probably-not/q/internal/check"
 	"github.com/probably-not/q/internal/consts"
 )
 
+var (
+	// ErrFactorTooLarge is returned when the size factor does not fit in the halves of the queue state.
+	ErrFactorTooLarge = check.ErrFactorTooLarge
+	// ErrFactorZero is returned when the size factor is zero or negative.
+	ErrFactorZero = check.ErrFactorZero
+)
+
 type Q uint32
 
 func NewQ() Q {
 	return 0
 }
 
+// NewQChecked creates a new queue after validating the size factor that will be passed to its operations.
+// If the factor can't be used with the queue, ErrFactorZero or ErrFactorTooLarge is returned.
+func NewQChecked(factor int) (Q, error) {
+	if err := check.FactorU32(factor); err != nil {
+		return 0, err
+	}
+
+	return NewQ(), nil
+}
+
 // Pop will calculate the position that can currently be popped from the queue.
 // It returns the position, along with a boolean indicating if the queue is empty or not.
 // If the queue is empty, `-1, true` will be returned.
 // If the queue is not empty, `pos, false` will be returned.
 func (q *Q) Pop(factor int) (int, bool) {
+	check.AssertFactorU32(factor)
 	acquired := atomic.LoadUint32((*uint32)(q))
 	mask := (uint32(1) << factor) - 1
 	head := acquired & mask
@@ -40,6 +59,7 @@ func (q *Q) PopCommit() {
 // If the queue is full, `-1, true` will be returned.
 // If the queue is not full, `pos, false` will be returned.
 func (q *Q) Push(factor int) (int, bool) {
+	check.AssertFactorU32(factor)
 	acquired := atomic.LoadUint32((*uint32)(q))
 	mask := (uint32(1) << factor) - 1
 	head := acquired & mask
@@ -62,3 +82,44 @@ func (q *Q) Push(factor int) (int, bool) {
 func (q *Q) PushCommit() {
 	atomic.AddUint32((*uint32)(q), 1)
 }
+
+// Len returns the number of jobs that are committed to the queue and not yet popped.
+// It is decoded from a single atomic load, and unlike a Push probe it has no side effects.
+func (q *Q) Len(factor int) int {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	return int((head - tail) & mask)
+}
+
+// Cap returns the number of jobs that the queue can hold, which is one less than its number of positions,
+// since a full queue always keeps one position free to tell it apart from an empty queue.
+func (q *Q) Cap(factor int) int {
+	check.AssertFactorU32(factor)
+	return (1 << factor) - 1
+}
+
+// IsEmpty reports whether the queue is empty, from a single atomic load and without side effects.
+func (q *Q) IsEmpty(factor int) bool {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	return head == tail
+}
+
+// IsFull reports whether the queue is full, from a single atomic load and without side effects.
+func (q *Q) IsFull(factor int) bool {
+	check.AssertFactorU32(factor)
+	acquired := atomic.LoadUint32((*uint32)(q))
+	mask := (uint32(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 16 & mask
+
+	return (head+uint32(1))&mask == tail
+}
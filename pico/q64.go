@@ -0,0 +1,120 @@
+package pico
+
+import (
+	"sync/atomic"
+
+	"github.com/probably-not/q/internal/check"
+	"github.com/probably-not/q/internal/consts"
+)
+
+// Q64 is the `uint64` backed variant of Q. The head and tail each get 32 bits instead of 16, which supports
+// size factors up to 31. Since Q64 is operated on atomically, it must be 64-bit aligned on 32-bit platforms.
+type Q64 uint64
+
+func NewQ64() Q64 {
+	return 0
+}
+
+// NewQ64Checked creates a new queue after validating the size factor that will be passed to its operations.
+// If the factor can't be used with the queue, ErrFactorZero or ErrFactorTooLarge is returned.
+func NewQ64Checked(factor int) (Q64, error) {
+	if err := check.FactorU64(factor); err != nil {
+		return 0, err
+	}
+
+	return NewQ64(), nil
+}
+
+// Pop will calculate the position that can currently be popped from the queue.
+// It returns the position, along with a boolean indicating if the queue is empty or not.
+// If the queue is empty, `-1, true` will be returned.
+// If the queue is not empty, `pos, false` will be returned.
+func (q *Q64) Pop(factor int) (int, bool) {
+	check.AssertFactorU64(factor)
+	acquired := atomic.LoadUint64((*uint64)(q))
+	mask := (uint64(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 32 & mask
+
+	if head == tail {
+		return -1, true
+	}
+
+	return int(tail), false
+}
+
+// PopCommit will commit the previously executed Pop operation to the queue.
+// This moves the index of the queue to the next pop-able index.
+func (q *Q64) PopCommit() {
+	atomic.AddUint64((*uint64)(q), consts.CommitPopU64)
+}
+
+// Push will calculate the position that can currently be pushed to in the queue.
+// It returns the position, along with a boolean indicating if the queue is full or not.
+// If the queue is full, `-1, true` will be returned.
+// If the queue is not full, `pos, false` will be returned.
+func (q *Q64) Push(factor int) (int, bool) {
+	check.AssertFactorU64(factor)
+	acquired := atomic.LoadUint64((*uint64)(q))
+	mask := (uint64(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 32 & mask
+	next := (head + uint64(1)) & mask
+
+	if acquired&consts.PushOverflowCheckU64 != 0 {
+		atomic.AddUint64((*uint64)(q), consts.PushOverflowProtectionU64)
+	}
+
+	if next == tail {
+		return -1, true
+	}
+
+	return int(head), false
+}
+
+// PushCommit will commit the previously executed Push operation to the queue.
+// This moves the index of the queue to the next push-able index.
+func (q *Q64) PushCommit() {
+	atomic.AddUint64((*uint64)(q), 1)
+}
+
+// Len returns the number of jobs that are committed to the queue and not yet popped.
+// It is decoded from a single atomic load, and unlike a Push probe it has no side effects.
+func (q *Q64) Len(factor int) int {
+	check.AssertFactorU64(factor)
+	acquired := atomic.LoadUint64((*uint64)(q))
+	mask := (uint64(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 32 & mask
+
+	return int((head - tail) & mask)
+}
+
+// Cap returns the number of jobs that the queue can hold, which is one less than its number of positions,
+// since a full queue always keeps one position free to tell it apart from an empty queue.
+func (q *Q64) Cap(factor int) int {
+	check.AssertFactorU64(factor)
+	return (1 << factor) - 1
+}
+
+// IsEmpty reports whether the queue is empty, from a single atomic load and without side effects.
+func (q *Q64) IsEmpty(factor int) bool {
+	check.AssertFactorU64(factor)
+	acquired := atomic.LoadUint64((*uint64)(q))
+	mask := (uint64(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 32 & mask
+
+	return head == tail
+}
+
+// IsFull reports whether the queue is full, from a single atomic load and without side effects.
+func (q *Q64) IsFull(factor int) bool {
+	check.AssertFactorU64(factor)
+	acquired := atomic.LoadUint64((*uint64)(q))
+	mask := (uint64(1) << factor) - 1
+	head := acquired & mask
+	tail := acquired >> 32 & mask
+
+	return (head+uint64(1))&mask == tail
+}
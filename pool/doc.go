@@ -0,0 +1,9 @@
+// Package pool contains a worker pool built on a `micro.Ring`.
+// The `micro` package describes a single producer/multiple consumer queue, which is exactly the shape of a worker pool:
+// submitted jobs are pushed to a ring of job slots, and a fixed number of worker goroutines pop them and run a handler per job.
+// Submissions are serialized with a mutex, so jobs can be submitted from any number of goroutines, while the
+// workers pop from the ring lock-free. The mutex is only held for a single push attempt, so a submitter that is
+// waiting for a free slot never holds up the context of another submitter.
+// A panic inside the handler is recovered, and only fails the job that caused it.
+// The result of a job can optionally be received through a `pool.Future`.
+package pool
@@ -0,0 +1,39 @@
+package pool
+
+import "context"
+
+// Future holds the result of a job that was submitted with SubmitFuture, once the job has been handled.
+type Future[R any] struct {
+	err    error
+	done   chan struct{}
+	result R
+}
+
+func newFuture[R any]() *Future[R] {
+	return &Future[R]{
+		done: make(chan struct{}),
+	}
+}
+
+// Done returns a channel that is closed once the job has been handled.
+func (f *Future[R]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait will block until the job has been handled, or until the context is done.
+// It returns the result and error of the handler, or the zero value of `R` and the context's error
+// if the context is done first.
+func (f *Future[R]) Wait(ctx context.Context) (R, error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
+	}
+}
+
+func (f *Future[R]) resolve(result R, err error) {
+	f.result, f.err = result, err
+	close(f.done)
+}
@@ -0,0 +1,203 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/probably-not/q/internal/check"
+	"github.com/probably-not/q/micro"
+)
+
+var (
+	// ErrPanic is wrapped by the error of a job whose handler panicked.
+	ErrPanic = errors.New("job handler panicked")
+	// ErrWorkers is returned by NewChecked when the number of workers is zero or negative.
+	ErrWorkers = errors.New("pool must have at least one worker")
+)
+
+type job[T any, R any] struct {
+	future *Future[R]
+	v      T
+}
+
+// Pool runs a handler for every submitted job on a fixed number of worker goroutines.
+type Pool[T any, R any] struct {
+	ring     *micro.Ring[*job[T, R]]
+	notEmpty *micro.Park
+	// notFull is signalled by the workers whenever they free a slot, so that a blocked submitter
+	// can wait for it together with its context.
+	notFull chan struct{}
+	handler func(T) (R, error)
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// New creates a Pool with a ring of the given size factor, and starts the workers.
+// The handler is called once for every submitted job, and its result is delivered to the job's Future if it has one.
+func New[T any, R any](queueSizeFactor int, workers int, handler func(T) (R, error)) *Pool[T, R] {
+	p := &Pool[T, R]{
+		ring:     micro.NewRing[*job[T, R]](queueSizeFactor),
+		notEmpty: micro.NewPark(),
+		notFull:  make(chan struct{}, 1),
+		handler:  handler,
+	}
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+// NewChecked creates a Pool after validating the size factor and the number of workers.
+// If the factor can't be used with the ring, `micro.ErrFactorZero` or `micro.ErrFactorTooLarge` is returned,
+// and if there are no workers to run the jobs, ErrWorkers is returned.
+func NewChecked[T any, R any](queueSizeFactor int, workers int, handler func(T) (R, error)) (*Pool[T, R], error) {
+	if err := check.FactorU32(queueSizeFactor); err != nil {
+		return nil, err
+	}
+
+	if workers <= 0 {
+		return nil, ErrWorkers
+	}
+
+	return New[T, R](queueSizeFactor, workers, handler), nil
+}
+
+// Submit will block until the job is pushed to the pool, or until the context is done.
+// It returns the context's error if the context is done first, or `micro.ErrClosed` if the pool is shut down.
+func (p *Pool[T, R]) Submit(ctx context.Context, v T) error {
+	return p.submit(ctx, &job[T, R]{v: v})
+}
+
+// SubmitFuture will block until the job is pushed to the pool, or until the context is done,
+// and returns a Future that receives the result of the job.
+// It returns the context's error if the context is done first, or `micro.ErrClosed` if the pool is shut down.
+func (p *Pool[T, R]) SubmitFuture(ctx context.Context, v T) (*Future[R], error) {
+	f := newFuture[R]()
+	if err := p.submit(ctx, &job[T, R]{v: v, future: f}); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// TrySubmit will attempt to push the job to the pool without blocking.
+// It returns `true` if the job was pushed, or `false` if the pool is full or shut down.
+func (p *Pool[T, R]) TrySubmit(v T) bool {
+	pushed, _ := p.tryPush(&job[T, R]{v: v})
+	return pushed
+}
+
+// Shutdown stops the pool from accepting jobs, and waits for the workers to handle the jobs that were already submitted.
+// If the context is done before the workers finish, the context's error is returned, and the workers keep
+// draining the remaining jobs in the background.
+// If the context is already done when Shutdown is called, its error is returned without shutting down the pool.
+func (p *Pool[T, R]) Shutdown(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.ring.Close()
+	p.mu.Unlock()
+	p.notEmpty.Notify()
+	p.signalNotFull() // Wake a blocked submitter, so that it sees the shutdown
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// submit retries the push until it succeeds, the pool is shut down, or the context is done.
+// The mutex is only held for a single attempt, so a blocked submitter never holds up
+// another submitter's context, or a Shutdown.
+func (p *Pool[T, R]) submit(ctx context.Context, j *job[T, R]) error {
+	for {
+		pushed, closed := p.tryPush(j)
+		if pushed {
+			if !p.ring.IsFull() {
+				p.signalNotFull() // Pass along a wake up for the slots that are left
+			}
+			return nil
+		}
+
+		if closed {
+			p.signalNotFull() // Pass the wake up along, so that every blocked submitter sees the shutdown
+			return micro.ErrClosed
+		}
+
+		select {
+		case <-ctx.Done():
+			p.signalNotFull() // Pass along a wake up that this submitter may have taken
+			return ctx.Err()
+		case <-p.notFull:
+		}
+	}
+}
+
+// tryPush pushes the job under the mutex, since submitters are the multiple producers that the ring doesn't support.
+// It reports whether the job was pushed, and if not, whether that is because the pool is shut down.
+func (p *Pool[T, R]) tryPush(j *job[T, R]) (bool, bool) {
+	p.mu.Lock()
+	pushed := p.ring.TryPush(j)
+	closed := !pushed && p.ring.IsClosed()
+	p.mu.Unlock()
+
+	if pushed {
+		p.notEmpty.Notify()
+	}
+
+	return pushed, closed
+}
+
+func (p *Pool[T, R]) signalNotFull() {
+	select {
+	case p.notFull <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Pool[T, R]) work() {
+	defer p.wg.Done()
+
+	for {
+		j, ok := p.ring.PopWait(p.notEmpty)
+		if !ok {
+			p.notEmpty.Notify() // Pass the wake up along, so that every parked worker sees the shutdown
+			return
+		}
+
+		p.signalNotFull()
+		if !p.ring.IsEmpty() {
+			p.notEmpty.Notify() // Wake another worker for the jobs that are left
+		}
+
+		result, err := p.run(j.v)
+		if j.future != nil {
+			j.future.resolve(result, err)
+		}
+	}
+}
+
+func (p *Pool[T, R]) run(v T) (result R, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+
+	return p.handler(v)
+}
@@ -0,0 +1,289 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/probably-not/q/micro"
+)
+
+func TestPoolSubmit(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		queueSizeFactor int
+		workers         int
+		submitters      int
+	}{
+		{
+			desc:            "Single submitter with a single worker",
+			queueSizeFactor: 6,
+			workers:         1,
+			submitters:      1,
+		},
+		{
+			desc:            "Multiple submitters with multiple workers",
+			queueSizeFactor: 6,
+			workers:         10,
+			submitters:      10,
+		},
+		{
+			desc:            "Multiple submitters with a ring smaller than the workers",
+			queueSizeFactor: 2,
+			workers:         10,
+			submitters:      10,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			sum := int64(0)
+			p := New(tC.queueSizeFactor, tC.workers, func(v int64) (struct{}, error) {
+				atomic.AddInt64(&sum, v)
+				return struct{}{}, nil
+			})
+
+			var wg sync.WaitGroup
+			producedSum := int64(0)
+			for s := 0; s < tC.submitters; s++ {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					for i := int64(0); i < 1000; i++ {
+						if err := p.Submit(context.Background(), i); err != nil {
+							subT.Errorf("unexpected submit error %v", err)
+							return
+						}
+						atomic.AddInt64(&producedSum, i)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if err := p.Shutdown(context.Background()); err != nil {
+				subT.Errorf("unexpected shutdown error %v", err)
+			}
+
+			if producedSum != sum {
+				subT.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+			}
+		})
+	}
+}
+
+func TestPoolSubmitFuture(t *testing.T) {
+	testCases := []struct {
+		expectedErr    error
+		desc           string
+		v              int
+		expectedResult int
+	}{
+		{
+			desc:           "Result of the handler is delivered to the future",
+			v:              2,
+			expectedResult: 4,
+			expectedErr:    nil,
+		},
+		{
+			desc:           "Error of the handler is delivered to the future",
+			v:              -1,
+			expectedResult: 0,
+			expectedErr:    errNegative,
+		},
+		{
+			desc:           "Panic in the handler is recovered and delivered to the future",
+			v:              0,
+			expectedResult: 0,
+			expectedErr:    ErrPanic,
+		},
+	}
+
+	p := New(6, 4, func(v int) (int, error) {
+		if v < 0 {
+			return 0, errNegative
+		}
+
+		if v == 0 {
+			panic("zero")
+		}
+
+		return v * 2, nil
+	})
+	defer p.Shutdown(context.Background())
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			f, err := p.SubmitFuture(context.Background(), tC.v)
+			if err != nil {
+				subT.Fatalf("unexpected submit error %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+
+			result, err := f.Wait(ctx)
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+
+			if tC.expectedResult != result {
+				subT.Errorf("expected the result to be %d, got %d", tC.expectedResult, result)
+			}
+		})
+	}
+}
+
+func TestPoolTrySubmit(t *testing.T) {
+	// Without workers nothing is popped, so the ring fills up
+	p := New(2, 0, func(v int) (int, error) { return v, nil })
+
+	for i := 0; i < 3; i++ {
+		if !p.TrySubmit(i) {
+			t.Errorf("expected submit number %d to be accepted", i)
+		}
+	}
+
+	if p.TrySubmit(3) {
+		t.Errorf("expected a submit to a full pool to be rejected")
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error %v", err)
+	}
+
+	if p.TrySubmit(4) {
+		t.Errorf("expected a submit to a shut down pool to be rejected")
+	}
+
+	if err := p.Submit(context.Background(), 4); !errors.Is(err, micro.ErrClosed) {
+		t.Errorf("expected the submit error to be %v, got %v", micro.ErrClosed, err)
+	}
+}
+
+func TestPoolShutdownTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	p := New(2, 1, func(v int) (int, error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return v, nil
+	})
+
+	// Fill the pool, so that the worker is blocked in the handler and every slot of the ring is taken
+	if err := p.Submit(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected submit error %v", err)
+	}
+	<-started
+	for p.TrySubmit(1) {
+	}
+
+	// A submitter that is blocked on the full pool without a deadline
+	blocked := make(chan error, 1)
+	go func() {
+		blocked <- p.Submit(context.Background(), 1)
+	}()
+	<-time.After(10 * time.Millisecond) // Allow the submitter to block
+
+	// A submitter with a deadline must give up on time, even while the other submitter is blocked
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := p.Submit(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the submit error to be %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the submit to give up after its deadline, took %v", elapsed)
+	}
+
+	// A shutdown with a context that is already done must return straight away
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the shutdown error to be %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the shutdown error to be %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, micro.ErrClosed) {
+			t.Errorf("expected the blocked submit error to be %v, got %v", micro.ErrClosed, err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Errorf("expected the blocked submitter to be woken by the shutdown")
+	}
+
+	close(release)
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error %v", err)
+	}
+}
+
+var errNegative = errors.New("negative value")
+
+func TestNewChecked(t *testing.T) {
+	testCases := []struct {
+		expectedErr     error
+		desc            string
+		queueSizeFactor int
+		workers         int
+	}{
+		{
+			desc:            "Factor and workers within the supported range are accepted",
+			queueSizeFactor: 6,
+			workers:         4,
+			expectedErr:     nil,
+		},
+		{
+			desc:            "Factor larger than the 16 bit halves allow is rejected",
+			queueSizeFactor: 16,
+			workers:         4,
+			expectedErr:     micro.ErrFactorTooLarge,
+		},
+		{
+			desc:            "Zero factor is rejected",
+			queueSizeFactor: 0,
+			workers:         4,
+			expectedErr:     micro.ErrFactorZero,
+		},
+		{
+			desc:            "Zero workers are rejected",
+			queueSizeFactor: 6,
+			workers:         0,
+			expectedErr:     ErrWorkers,
+		},
+		{
+			desc:            "Negative workers are rejected",
+			queueSizeFactor: 6,
+			workers:         -1,
+			expectedErr:     ErrWorkers,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			p, err := NewChecked(tC.queueSizeFactor, tC.workers, func(v int) (int, error) { return v, nil })
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+
+			if p != nil {
+				if err := p.Shutdown(context.Background()); err != nil {
+					subT.Errorf("unexpected error shutting down the pool %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+// Package priority contains a priority queue composed of a `micro.Ring` per priority level.
+// Level 0 is the highest priority, and Pop drains the highest non-empty level first, so callers don't need to write
+// their own selection logic around several queues. Every level keeps the semantics of a `micro.Ring`: it is safe
+// for a single producer per level, with any number of consumers popping from all of the levels lock-free.
+// Strict priority lets a busy high level starve the levels below it, so the queue has an optional aging mode,
+// where a non-empty level that has been passed over for too many pops is served before the levels above it.
+package priority
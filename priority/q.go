@@ -0,0 +1,138 @@
+package priority
+
+import (
+	"sync/atomic"
+
+	"github.com/probably-not/q/internal/check"
+	"github.com/probably-not/q/micro"
+)
+
+// Options configure how Pop chooses between the levels of a Q.
+type Options struct {
+	// AgeLimit is the number of pops that a non-empty level may be passed over for, before it is served ahead of
+	// the levels above it. If it is zero, Pop uses strict priority, and always serves the highest non-empty level.
+	AgeLimit int
+}
+
+// Q is a priority queue with a `micro.Ring` per level, where level 0 is the highest priority.
+type Q[T any] struct {
+	levels   []level[T]
+	ageLimit uint32
+	pops     uint32
+}
+
+type level[T any] struct {
+	ring *micro.Ring[T]
+	// served is the pop at which the level was last served, or last seen empty, for aging.
+	served uint32
+}
+
+// New creates a priority queue with the number of levels, each with a queue of the size factor.
+func New[T any](levels int, queueSizeFactor int, opts Options) *Q[T] {
+	q := &Q[T]{
+		levels:   make([]level[T], levels),
+		ageLimit: uint32(opts.AgeLimit),
+	}
+
+	for i := range q.levels {
+		q.levels[i].ring = micro.NewRing[T](queueSizeFactor)
+	}
+
+	return q
+}
+
+// NewChecked creates a new priority queue after validating the size factor.
+// If the factor can't be used with the queues, `micro.ErrFactorZero` or `micro.ErrFactorTooLarge` is returned.
+func NewChecked[T any](levels int, queueSizeFactor int, opts Options) (*Q[T], error) {
+	if err := check.FactorU32(queueSizeFactor); err != nil {
+		return nil, err
+	}
+
+	return New[T](levels, queueSizeFactor, opts), nil
+}
+
+// Levels returns the number of levels.
+func (q *Q[T]) Levels() int {
+	return len(q.levels)
+}
+
+// Push will attempt to push the value to the level, where level 0 is the highest priority.
+// It returns `true` if the value was pushed, or `false` if the level is full or closed.
+// Only a single producer may push to each level at a time. Push panics if the level is out of range.
+func (q *Q[T]) Push(level int, v T) bool {
+	return q.levels[level].ring.TryPush(v)
+}
+
+// Pop will attempt to pop a value from the highest non-empty level, or in aging mode, from a lower level
+// that has been passed over for more than the age limit.
+// It returns the value along with `true` if a value was popped, or the zero value of `T`
+// along with `false` if every level is empty, or closed and drained.
+// With multiple consumers, aging is approximate, since consumers may serve a starving level concurrently.
+func (q *Q[T]) Pop() (T, bool) {
+	var pop uint32
+	if q.ageLimit > 0 {
+		pop = atomic.AddUint32(&q.pops, 1)
+		if v, ok := q.popAged(pop); ok {
+			return v, true
+		}
+	}
+
+	for i := range q.levels {
+		if v, ok := q.levels[i].ring.TryPop(); ok {
+			if q.ageLimit > 0 {
+				atomic.StoreUint32(&q.levels[i].served, pop)
+			}
+			return v, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// Len returns the number of values that are committed to all of the levels and not yet popped.
+// Since the levels are not loaded atomically together, it is only a snapshot while values are being pushed and popped.
+func (q *Q[T]) Len() int {
+	n := 0
+	for i := range q.levels {
+		n += q.levels[i].ring.Len()
+	}
+
+	return n
+}
+
+// LenLevel returns the number of values that are committed to the level and not yet popped.
+func (q *Q[T]) LenLevel(level int) int {
+	return q.levels[level].ring.Len()
+}
+
+// Close closes every level, after which pushes are rejected, and pops drain the values that were already committed.
+func (q *Q[T]) Close() {
+	for i := range q.levels {
+		q.levels[i].ring.Close()
+	}
+}
+
+// popAged serves the lowest level that has been passed over for more than the age limit, if there is one.
+// Levels that are found empty are marked as served, since they haven't been waiting.
+func (q *Q[T]) popAged(pop uint32) (T, bool) {
+	for i := len(q.levels) - 1; i > 0; i-- {
+		l := &q.levels[i]
+		if l.ring.IsEmpty() {
+			atomic.StoreUint32(&l.served, pop)
+			continue
+		}
+
+		if pop-atomic.LoadUint32(&l.served) <= q.ageLimit {
+			continue
+		}
+
+		if v, ok := l.ring.TryPop(); ok {
+			atomic.StoreUint32(&l.served, pop)
+			return v, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,138 @@
+package priority
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPop(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		pushes   []int // Level of every push, where the value pushed is the level
+		expected []int
+		ageLimit int
+	}{
+		{
+			desc:     "Highest non-empty level is drained first",
+			pushes:   []int{2, 1, 0, 2, 1, 0},
+			expected: []int{0, 0, 1, 1, 2, 2},
+			ageLimit: 0,
+		},
+		{
+			desc:     "Empty levels are skipped",
+			pushes:   []int{2, 2},
+			expected: []int{2, 2},
+			ageLimit: 0,
+		},
+		{
+			desc:     "Lower levels are served once they were passed over for more than the age limit",
+			pushes:   []int{0, 0, 0, 0, 0, 0, 1, 1, 1},
+			expected: []int{0, 0, 1, 0, 0, 1, 0, 0, 1},
+			ageLimit: 2,
+		},
+		{
+			desc:     "Lowest starving level is served first",
+			pushes:   []int{0, 0, 0, 0, 1, 1, 2, 2},
+			expected: []int{0, 2, 1, 2, 1, 0, 0, 0},
+			ageLimit: 1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q := New[int](3, 4, Options{AgeLimit: tC.ageLimit})
+			for i, level := range tC.pushes {
+				if !q.Push(level, level) {
+					subT.Errorf("expected push number %d to be accepted", i)
+				}
+			}
+
+			if q.Len() != len(tC.pushes) {
+				subT.Errorf("expected the length to be %d, got %d", len(tC.pushes), q.Len())
+			}
+
+			for i, expected := range tC.expected {
+				v, ok := q.Pop()
+				if !ok || expected != v {
+					subT.Errorf("expected pop number %d to be from level %d, got %d (ok %t)", i, expected, v, ok)
+				}
+			}
+
+			if _, ok := q.Pop(); ok {
+				subT.Errorf("expected a pop from empty levels to fail")
+			}
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	q := New[int](2, 4, Options{})
+	q.Push(1, 1)
+	q.Close()
+
+	if q.Push(0, 0) {
+		t.Errorf("expected a push to a closed level to be rejected")
+	}
+
+	if v, ok := q.Pop(); !ok || v != 1 {
+		t.Errorf("expected the committed value to be drained after closing, got %d (ok %t)", v, ok)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	const levels = 3
+	q := New[int64](levels, 4, Options{AgeLimit: 4})
+	completedProducing := int32(0)
+
+	var producerWg sync.WaitGroup
+	producedSum := int64(0)
+	for l := 0; l < levels; l++ {
+		producerWg.Add(1)
+
+		// Every level has a single producer
+		go func(level int) {
+			defer producerWg.Done()
+
+			for i := int64(0); i < 1000; i++ {
+				if !q.Push(level, i) {
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					i--
+					continue
+				}
+				atomic.AddInt64(&producedSum, i)
+			}
+		}(l)
+	}
+
+	var consumerWg sync.WaitGroup
+	sum := int64(0)
+	for c := 0; c < 4; c++ {
+		consumerWg.Add(1)
+
+		go func() {
+			defer consumerWg.Done()
+
+			for {
+				v, ok := q.Pop()
+				if !ok {
+					if atomic.LoadInt32(&completedProducing) == 1 && q.Len() == 0 {
+						break
+					}
+					<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+					continue
+				}
+
+				atomic.AddInt64(&sum, v)
+			}
+		}()
+	}
+
+	producerWg.Wait()
+	atomic.StoreInt32(&completedProducing, 1)
+	consumerWg.Wait()
+
+	if producedSum != sum {
+		t.Errorf("expected the sum to be %d but got %d", producedSum, sum)
+	}
+}
@@ -0,0 +1,11 @@
+// Package shm contains a queue of byte records that lives in a memory-mapped file, so that it can be shared
+// between processes on the same machine without sockets.
+// The state of a `nano.Q` is a single `uint32`, so it can be placed inside the mapped file next to a fixed-size
+// region of slots, and every process that maps the file runs the same lock-free protocol on it.
+// The file starts with a header holding a magic number, the layout version, the size factor and the slot size,
+// followed by the state word and the slots, so that Open can validate a file that was created by another process.
+// Like `nano.Q`, the queue is safe for a single producer with multiple consumers, across all of the processes
+// that map it, and a consumer that fails its commit may have copied a record while the producer was writing it,
+// which is why records are copied out of the slots before the commit, and discarded if the commit fails.
+// Mapping files is only supported on Linux. On other platforms Create and Open return ErrUnsupported.
+package shm
@@ -0,0 +1,81 @@
+//go:build linux
+
+package shm
+
+import (
+	"os"
+	"syscall"
+)
+
+// Create creates a new queue file at the path, with `1<<factor` slots that each hold a record of up to slotSize bytes.
+// It fails if the file already exists, so that a queue that is in use is never overwritten.
+// If the factor can't be used with the queue, ErrFactorZero or ErrFactorTooLarge is returned.
+func Create(path string, factor, slotSize int) (*Queue, error) {
+	if err := validate(factor, slotSize); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := f.Truncate(int64(size(factor, slotSize))); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	mem, err := mmap(f, size(factor, slotSize))
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	initialize(mem, factor, slotSize)
+	return attach(mem)
+}
+
+// Open maps an existing queue file at the path, which may have been created by another process.
+// It returns ErrInvalidHeader if the file is not a queue file, or was written with a different layout.
+func Open(path string) (*Queue, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() < headerSize {
+		return nil, ErrInvalidHeader
+	}
+
+	mem, err := mmap(f, int(info.Size()))
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := attach(mem)
+	if err != nil {
+		syscall.Munmap(mem)
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// Close unmaps the queue file. The file itself, and the records in it, remain for other processes.
+// The queue must not be used after it is closed.
+func (q *Queue) Close() error {
+	mem := q.mem
+	q.mem = nil
+	return syscall.Munmap(mem)
+}
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package shm
+
+// Create is not supported on this platform, and always returns ErrUnsupported.
+func Create(path string, factor, slotSize int) (*Queue, error) {
+	return nil, ErrUnsupported
+}
+
+// Open is not supported on this platform, and always returns ErrUnsupported.
+func Open(path string) (*Queue, error) {
+	return nil, ErrUnsupported
+}
+
+// Close is not supported on this platform, and always returns ErrUnsupported.
+func (q *Queue) Close() error {
+	return ErrUnsupported
+}
@@ -0,0 +1,178 @@
+package shm
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/probably-not/q/internal/check"
+	"github.com/probably-not/q/nano"
+)
+
+const (
+	// Magic is the first word of every queue file.
+	Magic = 0x4d485351 // "QSHM" in little endian
+	// Version is the version of the file layout that this package reads and writes.
+	Version = 1
+)
+
+// The header is padded to a cache line, so that the state word doesn't share a line with the slots.
+const (
+	magicOffset    = 0
+	versionOffset  = 4
+	factorOffset   = 8
+	slotSizeOffset = 12
+	stateOffset    = 16
+	headerSize     = 64
+	lengthSize     = 4
+)
+
+var (
+	// ErrFactorTooLarge is returned when the size factor does not fit in the halves of the queue state.
+	ErrFactorTooLarge = check.ErrFactorTooLarge
+	// ErrFactorZero is returned when the size factor is zero or negative.
+	ErrFactorZero = check.ErrFactorZero
+	// ErrUnsupported is returned by Create and Open on platforms where mapping files is not supported.
+	ErrUnsupported = errors.New("shared memory queues are not supported on this platform")
+	// ErrInvalidHeader is returned by Open when the file is not a queue file, or was written with a different layout.
+	ErrInvalidHeader = errors.New("file is not a shared memory queue")
+	// ErrSlotSize is returned by Create when the slot size is zero or negative.
+	ErrSlotSize = errors.New("slot size must be positive")
+	// ErrRecordTooLarge is returned by Push when the record does not fit in a slot.
+	ErrRecordTooLarge = errors.New("record is larger than the slot size")
+	// ErrFull is returned by Push when the queue is full.
+	ErrFull = errors.New("queue is full")
+	// ErrEmpty is returned by Pop when the queue is empty.
+	ErrEmpty = errors.New("queue is empty")
+)
+
+// Queue is a queue of byte records in a memory-mapped file.
+type Queue struct {
+	q        *nano.Q
+	mem      []byte
+	factor   int
+	slotSize int
+	stride   int
+}
+
+// Push copies the record into the next slot of the queue and commits it.
+// It returns ErrFull if the queue is full, or ErrRecordTooLarge if the record does not fit in a slot.
+// Only a single producer, across every process that maps the file, may push to the queue.
+func (q *Queue) Push(record []byte) error {
+	if len(record) > q.slotSize {
+		return ErrRecordTooLarge
+	}
+
+	pos, isFull := q.q.Push(q.factor)
+	if isFull {
+		return ErrFull
+	}
+
+	slot := q.slot(pos)
+	binary.LittleEndian.PutUint32(slot, uint32(len(record)))
+	copy(slot[lengthSize:], record)
+	q.q.PushCommit()
+	return nil
+}
+
+// Pop appends the next record of the queue to dst, and returns the extended slice.
+// It returns ErrEmpty if the queue is empty.
+// If another consumer commits the same position first, Pop retries until it either
+// commits a position of its own or finds the queue empty.
+func (q *Queue) Pop(dst []byte) ([]byte, error) {
+	for {
+		pos, savepoint, isEmpty := q.q.Pop(q.factor)
+		if isEmpty {
+			return dst, ErrEmpty
+		}
+
+		// The slot may be rewritten while a losing consumer copies it, so its length is only trusted after the commit
+		slot := q.slot(pos)
+		n := int(binary.LittleEndian.Uint32(slot))
+		if n > q.slotSize {
+			n = q.slotSize
+		}
+
+		out := append(dst, slot[lengthSize:lengthSize+n]...)
+		if q.q.PopCommit(savepoint) {
+			return out, nil
+		}
+	}
+}
+
+// Len returns the number of records that are committed to the queue and not yet popped.
+func (q *Queue) Len() int {
+	return q.q.Len(q.factor)
+}
+
+// Cap returns the number of records that the queue can hold.
+func (q *Queue) Cap() int {
+	return q.q.Cap(q.factor)
+}
+
+// SlotSize returns the largest record that the queue can hold.
+func (q *Queue) SlotSize() int {
+	return q.slotSize
+}
+
+func (q *Queue) slot(pos int) []byte {
+	start := headerSize + pos*q.stride
+	return q.mem[start : start+q.stride]
+}
+
+// size returns the size of a file holding a queue of the given factor and slot size.
+func size(factor, slotSize int) int {
+	return headerSize + (1<<factor)*stride(slotSize)
+}
+
+// stride returns the distance between slots, which keeps the length of every slot aligned.
+func stride(slotSize int) int {
+	return (lengthSize + slotSize + 7) &^ 7
+}
+
+func validate(factor, slotSize int) error {
+	if err := check.FactorU32(factor); err != nil {
+		return err
+	}
+
+	if slotSize <= 0 {
+		return ErrSlotSize
+	}
+
+	return nil
+}
+
+// initialize writes the header of a new queue file. The magic number is written last,
+// so that a process that opens the file while it is being created rejects it instead of reading a partial header.
+func initialize(mem []byte, factor, slotSize int) {
+	binary.LittleEndian.PutUint32(mem[versionOffset:], Version)
+	binary.LittleEndian.PutUint32(mem[factorOffset:], uint32(factor))
+	binary.LittleEndian.PutUint32(mem[slotSizeOffset:], uint32(slotSize))
+	atomic.StoreUint32((*uint32)(unsafe.Pointer(&mem[magicOffset])), Magic)
+}
+
+// attach validates the header of a mapped queue file, and returns the queue that it holds.
+func attach(mem []byte) (*Queue, error) {
+	if len(mem) < headerSize || atomic.LoadUint32((*uint32)(unsafe.Pointer(&mem[magicOffset]))) != Magic {
+		return nil, ErrInvalidHeader
+	}
+
+	if binary.LittleEndian.Uint32(mem[versionOffset:]) != Version {
+		return nil, ErrInvalidHeader
+	}
+
+	factor := int(binary.LittleEndian.Uint32(mem[factorOffset:]))
+	slotSize := int(binary.LittleEndian.Uint32(mem[slotSizeOffset:]))
+	if validate(factor, slotSize) != nil || len(mem) != size(factor, slotSize) {
+		return nil, ErrInvalidHeader
+	}
+
+	return &Queue{
+		q:        (*nano.Q)(unsafe.Pointer(&mem[stateOffset])),
+		mem:      mem,
+		factor:   factor,
+		slotSize: slotSize,
+		stride:   stride(slotSize),
+	}, nil
+}
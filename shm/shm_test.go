@@ -0,0 +1,280 @@
+//go:build linux
+
+package shm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPushPop(t *testing.T) {
+	testCases := []struct {
+		expectedErr error
+		desc        string
+		records     [][]byte
+		factor      int
+		slotSize    int
+	}{
+		{
+			desc:        "Records are popped in the order they were pushed",
+			records:     [][]byte{[]byte("a"), []byte("bc"), []byte("def")},
+			factor:      2,
+			slotSize:    8,
+			expectedErr: nil,
+		},
+		{
+			desc:        "Empty records and records that fill a slot are kept intact",
+			records:     [][]byte{{}, []byte("12345678")},
+			factor:      2,
+			slotSize:    8,
+			expectedErr: nil,
+		},
+		{
+			desc:        "Records larger than a slot are rejected",
+			records:     [][]byte{[]byte("123456789")},
+			factor:      2,
+			slotSize:    8,
+			expectedErr: ErrRecordTooLarge,
+		},
+		{
+			desc:        "Pushes to a full queue are rejected",
+			records:     [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")},
+			factor:      2,
+			slotSize:    8,
+			expectedErr: ErrFull,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q, err := Create(filepath.Join(subT.TempDir(), "queue"), tC.factor, tC.slotSize)
+			if err != nil {
+				subT.Fatalf("unexpected error creating the queue %v", err)
+			}
+			defer q.Close()
+
+			var pushed [][]byte
+			for _, record := range tC.records {
+				if err = q.Push(record); err != nil {
+					break
+				}
+				pushed = append(pushed, record)
+			}
+
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the push error to be %v, got %v", tC.expectedErr, err)
+			}
+
+			if q.Len() != len(pushed) {
+				subT.Errorf("expected the length to be %d, got %d", len(pushed), q.Len())
+			}
+
+			for i, expected := range pushed {
+				record, err := q.Pop(nil)
+				if err != nil {
+					subT.Errorf("unexpected error at pop number %d: %v", i, err)
+					return
+				}
+
+				if !bytes.Equal(expected, record) {
+					subT.Errorf("expected pop number %d to be %q, got %q", i, expected, record)
+				}
+			}
+
+			if _, err := q.Pop(nil); !errors.Is(err, ErrEmpty) {
+				subT.Errorf("expected the pop error on an empty queue to be %v, got %v", ErrEmpty, err)
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	testCases := []struct {
+		expectedErr error
+		desc        string
+		factor      int
+		slotSize    int
+	}{
+		{
+			desc:        "Valid factor and slot size are accepted",
+			factor:      6,
+			slotSize:    64,
+			expectedErr: nil,
+		},
+		{
+			desc:        "Zero factor is rejected",
+			factor:      0,
+			slotSize:    64,
+			expectedErr: ErrFactorZero,
+		},
+		{
+			desc:        "Factor larger than the state halves is rejected",
+			factor:      16,
+			slotSize:    64,
+			expectedErr: ErrFactorTooLarge,
+		},
+		{
+			desc:        "Zero slot size is rejected",
+			factor:      6,
+			slotSize:    0,
+			expectedErr: ErrSlotSize,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			q, err := Create(filepath.Join(subT.TempDir(), "queue"), tC.factor, tC.slotSize)
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+
+			if err == nil {
+				q.Close()
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "queue")
+	q, err := Create(path, 6, 64)
+	if err != nil {
+		t.Fatalf("unexpected error creating the queue %v", err)
+	}
+	defer q.Close()
+
+	if _, err := Create(path, 6, 64); !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected creating an existing queue to return %v, got %v", os.ErrExist, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	testCases := []struct {
+		corrupt     func(file []byte) []byte
+		expectedErr error
+		desc        string
+	}{
+		{
+			desc:        "Valid queue file is opened",
+			corrupt:     func(file []byte) []byte { return file },
+			expectedErr: nil,
+		},
+		{
+			desc: "File with the wrong magic number is rejected",
+			corrupt: func(file []byte) []byte {
+				binary.LittleEndian.PutUint32(file[magicOffset:], 0)
+				return file
+			},
+			expectedErr: ErrInvalidHeader,
+		},
+		{
+			desc: "File with a different layout version is rejected",
+			corrupt: func(file []byte) []byte {
+				binary.LittleEndian.PutUint32(file[versionOffset:], Version+1)
+				return file
+			},
+			expectedErr: ErrInvalidHeader,
+		},
+		{
+			desc: "File that does not match the factor and slot size is rejected",
+			corrupt: func(file []byte) []byte {
+				binary.LittleEndian.PutUint32(file[factorOffset:], 7)
+				return file
+			},
+			expectedErr: ErrInvalidHeader,
+		},
+		{
+			desc:        "File shorter than a header is rejected",
+			corrupt:     func(file []byte) []byte { return file[:headerSize-1] },
+			expectedErr: ErrInvalidHeader,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(subT *testing.T) {
+			path := filepath.Join(subT.TempDir(), "queue")
+			q, err := Create(path, 6, 64)
+			if err != nil {
+				subT.Fatalf("unexpected error creating the queue %v", err)
+			}
+			q.Close()
+
+			file, err := os.ReadFile(path)
+			if err != nil {
+				subT.Fatalf("unexpected error reading the queue file %v", err)
+			}
+
+			if err := os.WriteFile(path, tC.corrupt(file), 0o600); err != nil {
+				subT.Fatalf("unexpected error writing the queue file %v", err)
+			}
+
+			q, err = Open(path)
+			if !errors.Is(err, tC.expectedErr) {
+				subT.Errorf("expected the returned error to be %v, got %v", tC.expectedErr, err)
+			}
+
+			if err == nil {
+				q.Close()
+			}
+		})
+	}
+}
+
+func TestCrossProcess(t *testing.T) {
+	if path := os.Getenv("Q_SHM_PRODUCER"); path != "" {
+		produce(t, path)
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "queue")
+	q, err := Create(path, 4, 32)
+	if err != nil {
+		t.Fatalf("unexpected error creating the queue %v", err)
+	}
+	defer q.Close()
+
+	// The test binary is run again as the producer process, which opens the same file
+	producer := exec.Command(os.Args[0], "-test.run=^TestCrossProcess$")
+	producer.Env = append(os.Environ(), "Q_SHM_PRODUCER="+path)
+	if err := producer.Start(); err != nil {
+		t.Fatalf("unexpected error starting the producer process %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for i := 0; i < 1000; i++ {
+		record, err := q.Pop(nil)
+		if errors.Is(err, ErrEmpty) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for record number %d", i)
+			}
+			<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+			i--
+			continue
+		}
+
+		if expected := fmt.Sprintf("record-%d", i); string(record) != expected {
+			t.Errorf("expected record number %d to be %q, got %q", i, expected, record)
+		}
+	}
+
+	if err := producer.Wait(); err != nil {
+		t.Errorf("unexpected error from the producer process %v", err)
+	}
+}
+
+func produce(t *testing.T, path string) {
+	q, err := Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error opening the queue %v", err)
+	}
+	defer q.Close()
+
+	for i := 0; i < 1000; i++ {
+		if err := q.Push([]byte(fmt.Sprintf("record-%d", i))); err != nil {
+			<-time.After(1 * time.Millisecond) // Allow some sleeping so that it's not a pure busy loop
+			i--
+		}
+	}
+}